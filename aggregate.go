@@ -0,0 +1,141 @@
+//go:build go1.18
+
+package zset
+
+// Monoid describes how to aggregate the items of a set into a value of type
+// A. Combine must be associative and Identity must be its identity element,
+// e.g. sum with 0, max with the lowest value, or count with 0.
+type Monoid[T, A any] struct {
+	Identity A
+	// Lift returns the aggregate of a single item.
+	Lift func(item T) A
+	// Combine returns the aggregate of a followed by b.
+	Combine func(a, b A) A
+}
+
+// augmenter maintains extra per level data on the nodes of a skip list.
+type augmenter[K comparable, T any] interface {
+	// init prepares the storage of node n, whose levels have been allocated.
+	init(n *node[K, T])
+	// free releases the storage of node n, which has been removed.
+	free(n *node[K, T])
+	// update recomputes level i of node x from level i-1 of the nodes it spans.
+	update(x *node[K, T], i int)
+}
+
+// monoidAugmenter keeps, for each level of each node, the aggregate of the
+// items it spans: aggs[x][i] is the aggregate of the items in
+// (x, x.level[i].forward]. They are kept apart from the nodes, so that sets
+// which do not aggregate do not pay for them.
+type monoidAugmenter[K comparable, T, A any] struct {
+	m    Monoid[T, A]
+	aggs map[*node[K, T]][]A
+}
+
+func (ma *monoidAugmenter[K, T, A]) init(n *node[K, T]) {
+	ma.aggs[n] = make([]A, len(n.level))
+}
+
+func (ma *monoidAugmenter[K, T, A]) free(n *node[K, T]) {
+	delete(ma.aggs, n)
+}
+
+func (ma *monoidAugmenter[K, T, A]) update(x *node[K, T], i int) {
+	aggs := ma.aggs[x]
+	if i == 0 {
+		if y := x.level[0].forward; y != nil {
+			aggs[0] = ma.m.Lift(y.item)
+		} else {
+			aggs[0] = ma.m.Identity
+		}
+		return
+	}
+	agg := ma.m.Identity
+	for y, end := x, x.level[i].forward; y != end; y = y.level[i-1].forward {
+		agg = ma.m.Combine(agg, ma.aggs[y][i-1])
+	}
+	aggs[i] = agg
+}
+
+// Aggregated is a ZSet which maintains an aggregate of its items, so that the
+// aggregate of any rank or score range can be computed in O(log N).
+type Aggregated[K comparable, T, A any] struct {
+	*ZSet[K, T]
+	m   Monoid[T, A]
+	aug *monoidAugmenter[K, T, A]
+}
+
+// NewAggregated creates a new ZSet which aggregates its items with m.
+func NewAggregated[K comparable, T, A any](less LessFunc[T], m Monoid[T, A]) *Aggregated[K, T, A] {
+	zs := New[K](less)
+	aug := &monoidAugmenter[K, T, A]{m: m, aggs: make(map[*node[K, T]][]A)}
+	zs.sl.aug = aug
+	aug.init(zs.sl.header)
+	for i := range zs.sl.header.level {
+		aug.update(zs.sl.header, i)
+	}
+	return &Aggregated[K, T, A]{ZSet: zs, m: m, aug: aug}
+}
+
+// AggregateRange returns the aggregate of the items with in index range
+// [start, end]. The <start> and <stop> arguments represent zero-based indexes,
+// and may be negative like in Range.
+func (a *Aggregated[K, T, A]) AggregateRange(start, end int) A {
+	llen := a.sl.length
+	if start < 0 {
+		start = llen + start
+	}
+	if end < 0 {
+		end = llen + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end || start >= llen {
+		return a.m.Identity
+	}
+	if end >= llen {
+		end = llen - 1
+	}
+	return a.aggregate(start+1, end+1)
+}
+
+// AggregateByScore returns the aggregate of the items within the range
+// [min, max]. If min is nil, it represents negative infinity. If max is nil,
+// it represents positive infinity.
+func (a *Aggregated[K, T, A]) AggregateByScore(min, max func(i T) bool) A {
+	minRank, maxRank := 1, a.sl.length
+	if min != nil {
+		var n *node[K, T]
+		if n, minRank = a.sl.findNext(min); n == nil {
+			return a.m.Identity
+		}
+	}
+	if max != nil {
+		_, maxRank = a.sl.findPrev(max)
+	}
+	if minRank > maxRank {
+		return a.m.Identity
+	}
+	return a.aggregate(minRank, maxRank)
+}
+
+// aggregate returns the aggregate of the items with 1-based ranks in
+// [from, to].
+func (a *Aggregated[K, T, A]) aggregate(from, to int) A {
+	x, rank := a.sl.header, from-1
+	if rank > 0 {
+		x = a.sl.getNodeByRank(rank)
+	}
+	agg := a.m.Identity
+	for rank < to {
+		i := len(x.level) - 1
+		for x.level[i].forward == nil || rank+x.level[i].span > to {
+			i--
+		}
+		agg = a.m.Combine(agg, a.aug.aggs[x][i])
+		rank += x.level[i].span
+		x = x.level[i].forward
+	}
+	return agg
+}
@@ -0,0 +1,116 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func newSumAggregated() *Aggregated[string, TestRank, int] {
+	return NewAggregated[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	}, Monoid[TestRank, int]{
+		Identity: 0,
+		Lift:     func(i TestRank) int { return i.score },
+		Combine:  func(a, b int) int { return a + b },
+	})
+}
+
+func TestAggregateRange(t *testing.T) {
+	zs := newSumAggregated()
+	if s := zs.AggregateRange(0, -1); s != 0 {
+		t.Error("AggregateRange on empty set", s)
+	}
+	for _, v := range perm(1000) {
+		zs.Add(v.member, v)
+	}
+	sum := func(from, to int) (s int) {
+		for i := from; i <= to; i++ {
+			s += i
+		}
+		return
+	}
+	for i := 0; i < 1000; i++ {
+		start, end := rand.Intn(1000), rand.Intn(1000)
+		expect := 0
+		if start <= end {
+			expect = sum(start, end)
+		}
+		if s := zs.AggregateRange(start, end); s != expect {
+			t.Error("AggregateRange error", start, end, s, expect)
+		}
+	}
+	if s := zs.AggregateRange(-10, -1); s != sum(990, 999) {
+		t.Error("AggregateRange error", s)
+	}
+	if s := zs.AggregateByScore(func(i TestRank) bool {
+		return i.score >= 100
+	}, func(i TestRank) bool {
+		return i.score <= 200
+	}); s != sum(100, 200) {
+		t.Error("AggregateByScore error", s)
+	}
+	if s := zs.AggregateByScore(nil, nil); s != sum(0, 999) {
+		t.Error("AggregateByScore error", s)
+	}
+	if s := zs.AggregateByScore(func(i TestRank) bool {
+		return i.score > 2000
+	}, nil); s != 0 {
+		t.Error("AggregateByScore error", s)
+	}
+}
+
+// TestAggregateUpdate checks the aggregates with an order dependent monoid
+// after random adds, updates and removes, including ties.
+func TestAggregateUpdate(t *testing.T) {
+	zs := NewAggregated[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	}, Monoid[TestRank, string]{
+		Lift:    func(i TestRank) string { return i.member + "," },
+		Combine: func(a, b string) string { return a + b },
+	})
+	model := make(map[string]TestRank)
+	for i := 0; i < 5000; i++ {
+		key := strconv.Itoa(rand.Intn(200))
+		if rand.Intn(4) == 0 {
+			zs.Remove(key)
+			delete(model, key)
+		} else {
+			item := TestRank{member: key, score: rand.Intn(50)}
+			zs.Add(key, item)
+			model[key] = item
+		}
+
+		if i%100 != 0 {
+			continue
+		}
+		var expect []string
+		zs.Range(0, -1, false, func(i TestRank, _ int) bool {
+			expect = append(expect, i.member)
+			return true
+		})
+		if len(expect) != len(model) {
+			t.Fatal("length error", len(expect), len(model))
+		}
+		for j := 0; j < 20; j++ {
+			start, end := rand.Intn(len(expect)+1), rand.Intn(len(expect)+1)
+			var s string
+			for k := start; k <= end && k < len(expect); k++ {
+				s += expect[k] + ","
+			}
+			if agg := zs.AggregateRange(start, end); agg != s {
+				t.Fatal("AggregateRange error", start, end, agg, s)
+			}
+		}
+	}
+
+	if agg := zs.AggregateByScore(nil, nil); agg != zs.AggregateRange(0, -1) {
+		t.Error("AggregateByScore error", agg)
+	}
+	// the aggregates of the removed nodes are released; the header has some.
+	if n := len(zs.aug.aggs); n != zs.Length()+1 {
+		t.Error("aggregates kept", n, zs.Length())
+	}
+}
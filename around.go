@@ -0,0 +1,35 @@
+//go:build go1.18
+
+package zset
+
+// Around calls the iterator for the element of key and its neighbors: at most
+// before elements ranked before it and at most after elements ranked after it,
+// in rank order, until iterator return false. It does nothing if key does not
+// exist. The neighbors are reached by following the links of the element, so
+// it costs one rank computation and a step per element visited.
+func (zs *ZSet[K, T]) Around(key K, before, after int, reverse bool, iterator ItemIterator[T]) {
+	n := zs.lookup(key)
+	if n == nil {
+		return
+	}
+	rank := zs.ord.rank(n, reverse)
+	next := func(x *node[K, T], backward bool) *node[K, T] {
+		if backward {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+	count := 1 + after
+	for ; before > 0 && next(n, !reverse) != nil; before-- {
+		n = next(n, !reverse)
+		rank--
+		count++
+	}
+	for ; n != nil && count > 0; count-- {
+		if !iterator(n.item, rank) {
+			return
+		}
+		n = next(n, reverse)
+		rank++
+	}
+}
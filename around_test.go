@@ -0,0 +1,67 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestAround(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(20) {
+		zs.Add(v.member, v)
+	}
+	tests := []struct {
+		key           string
+		before, after int
+		reverse       bool
+		want          []int // scores
+	}{
+		{"10", 2, 2, false, []int{8, 9, 10, 11, 12}},
+		{"10", 2, 2, true, []int{12, 11, 10, 9, 8}},
+		{"1", 3, 1, false, []int{0, 1, 2}},
+		{"18", 0, 5, false, []int{18, 19}},
+		{"18", 3, 1, true, []int{19, 18, 17}},
+		{"0", 0, 0, false, []int{0}},
+		{"0", 100, 100, true, rev(20)},
+		{"x", 2, 2, false, nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		zs.Around(tt.key, tt.before, tt.after, tt.reverse, func(v TestRank, rank int) bool {
+			if want := zs.Rank(v.member, tt.reverse); rank != want {
+				t.Error("Around rank error", tt.key, v, rank, want)
+			}
+			got = append(got, v.score)
+			return true
+		})
+		if len(got) != len(tt.want) {
+			t.Fatal("Around error", tt.key, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatal("Around error", tt.key, got, tt.want)
+			}
+		}
+	}
+
+	var n int
+	zs.Around("10", 5, 5, false, func(v TestRank, rank int) bool {
+		n++
+		return v.member != strconv.Itoa(9)
+	})
+	if n != 5 {
+		t.Error("Around stop error", n)
+	}
+}
+
+// rev returns the integers of [0, n) in decreasing order.
+func rev(n int) (out []int) {
+	for i := n - 1; i >= 0; i-- {
+		out = append(out, i)
+	}
+	return
+}
@@ -0,0 +1,152 @@
+//go:build go1.18
+
+package zset
+
+// Backend selects the ordered structure of a ZSet.
+type Backend int
+
+const (
+	// BackendSkipList orders the set with a skip list, like redis. It is the
+	// default, and the only backend supported by Aggregated and the compact
+	// encoding.
+	BackendSkipList Backend = iota
+	// BackendBTree orders the set with a counted B+ tree. It allocates fewer
+	// and more compact nodes, which makes searches more cache friendly on
+	// large sets.
+	BackendBTree
+)
+
+// backend orders the nodes of a ZSet. Whatever the backend, the nodes are
+// linked in order by level[0].forward and backward, so they are iterated
+// without it.
+type backend[K comparable, T any] interface {
+	len() int
+	// lessThan calls the LessFunc of the backend.
+	lessThan(a, b T) bool
+	// insert adds a node before the items equal to item.
+	insert(key K, item T) *node[K, T]
+	// load fills the empty backend with copies of count nodes starting at n.
+	load(n *node[K, T], count int)
+	delete(n *node[K, T]) T
+	// updateItem sets the item of n if n stays at the same rank, and reports
+	// whether it did.
+	updateItem(n *node[K, T], item T) bool
+	// rank returns the 1-based rank of n, or 0 if not exist.
+	rank(n *node[K, T], reverse bool) int
+	// getNodeByRank returns the node of 1-based rank, or nil.
+	getNodeByRank(rank int) *node[K, T]
+	// advance returns the node of 1-based rank target, starting from node x
+	// of rank rank, or from the start if x is nil. The target must be in
+	// [rank, length].
+	advance(x *node[K, T], rank, target int) *node[K, T]
+	// findNext returns the first node for which greater returns true, and
+	// its 1-based rank. The node is nil if there is none.
+	findNext(greater func(i T) bool) (*node[K, T], int)
+	// findPrev returns the last node for which less returns true, and its
+	// 1-based rank. The rank is 0 if there is none.
+	findPrev(less func(i T) bool) (*node[K, T], int)
+	getMinNode() *node[K, T]
+	getMaxNode() *node[K, T]
+	// hint tells the backend that the next operation is close to node n.
+	hint(n *node[K, T])
+	// empty returns a new empty backend with the same settings.
+	empty() backend[K, T]
+	// validate checks the invariants of the backend, and returns the 1-based
+	// rank of every node.
+	validate() (map[*node[K, T]]int, error)
+	// stats fills the structure fields and counters of s.
+	stats(s *Stats)
+}
+
+// rangeByScore implements ZSet.RangeByScore.
+func rangeByScore[K comparable, T any](b backend[K, T], min, max func(i T) bool, reverse bool, iterator ItemIterator[T]) {
+	llen := b.len()
+	var minNode, maxNode *node[K, T]
+	var minRank, maxRank int
+	if min == nil {
+		minNode = b.getMinNode()
+		minRank = 1
+	} else {
+		minNode, minRank = b.findNext(min)
+	}
+	if minNode == nil {
+		return
+	}
+	if max == nil {
+		maxNode = b.getMaxNode()
+		maxRank = llen
+	} else {
+		maxNode, maxRank = b.findPrev(max)
+	}
+	if maxNode == nil {
+		return
+	}
+	if reverse {
+		n := maxNode
+		for i := maxRank; i >= minRank; i-- {
+			if iterator(n.item, llen-i+1) {
+				n = n.backward
+			} else {
+				break
+			}
+		}
+	} else {
+		n := minNode
+		for i := minRank; i <= maxRank; i++ {
+			if iterator(n.item, i) {
+				n = n.level[0].forward
+			} else {
+				break
+			}
+		}
+	}
+}
+
+// rangeByRank implements ZSet.Range.
+func rangeByRank[K comparable, T any](b backend[K, T], start, end int, reverse bool, iterator ItemIterator[T]) {
+	rangeNodes(b, start, end, reverse, func(n *node[K, T], rank int) bool {
+		return iterator(n.item, rank)
+	})
+}
+
+// rangeNodes calls iterator for the nodes of index range [start, end] and
+// their ranks, like rangeByRank.
+func rangeNodes[K comparable, T any](b backend[K, T], start, end int, reverse bool, iterator func(n *node[K, T], rank int) bool) {
+	llen := b.len()
+	if start < 0 {
+		start = llen + start
+	}
+	if end < 0 {
+		end = llen + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end || start >= llen {
+		return
+	}
+	if end >= llen {
+		end = llen - 1
+	}
+
+	rangeLen := end - start + 1
+	if reverse {
+		ln := b.getNodeByRank(llen - start)
+		for i := 1; i <= rangeLen; i++ {
+			if iterator(ln, start+i) {
+				ln = ln.backward
+			} else {
+				break
+			}
+		}
+	} else {
+		ln := b.getNodeByRank(start + 1)
+		for i := 1; i <= rangeLen; i++ {
+			if iterator(ln, start+i) {
+				ln = ln.level[0].forward
+			} else {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+//go:build go1.18
+
+package zset
+
+import "sort"
+
+// GetMany is like Get for several keys, like ZMSCORE in redis. found[i]
+// reports whether keys[i] exists, in which case items[i] is its item.
+func (zs *ZSet[K, T]) GetMany(keys []K) (items []T, found []bool) {
+	items = make([]T, len(keys))
+	found = make([]bool, len(keys))
+	for i, key := range keys {
+		if n := zs.lookup(key); n != nil {
+			items[i], found[i] = n.item, true
+		}
+	}
+	return
+}
+
+// RankMany is like Rank for several keys: ranks[i] is the 1-based rank of
+// keys[i], or 0 if not exist. With the skip list backend, the elements are
+// sorted and their ranks are found in a single walk of the list, instead of
+// one search per key.
+func (zs *ZSet[K, T]) RankMany(keys []K, reverse bool) (ranks []int) {
+	ranks = make([]int, len(keys))
+	index := make([]int, 0, len(keys))
+	nodes := make([]*node[K, T], len(keys))
+	for i, key := range keys {
+		if n := zs.lookup(key); n != nil {
+			nodes[i] = n
+			index = append(index, i)
+		}
+	}
+	if zs.sl == nil {
+		for _, i := range index {
+			ranks[i] = zs.ord.rank(nodes[i], reverse)
+		}
+		return
+	}
+	compares := 0
+	sort.Slice(index, func(a, b int) bool {
+		return zs.sl.lessCount(nodes[index[a]].item, nodes[index[b]].item, &compares)
+	})
+	zs.sl.counters.compare(compares)
+	sorted := make([]*node[K, T], len(index))
+	for j, i := range index {
+		sorted[j] = nodes[i]
+	}
+	sortedRanks := make([]int, len(index))
+	zs.sl.rankSorted(sorted, sortedRanks)
+	for j, i := range index {
+		ranks[i] = sortedRanks[j]
+		if reverse {
+			ranks[i] = zs.sl.length - ranks[i] + 1
+		}
+	}
+	return
+}
+
+// rankSorted sets ranks[i] to the 1-based rank of nodes[i]. The nodes must be
+// sorted by item, in any order among equal items. The position reached at
+// every level only moves forward, so the list is walked once.
+func (sl *skipList[K, T]) rankSorted(nodes []*node[K, T], ranks []int) {
+	var update [DefaultMaxLevel]*node[K, T]
+	var rank [DefaultMaxLevel]int
+	for i := 0; i < sl.level; i++ {
+		update[i] = sl.header
+	}
+	steps, compares := 0, 0
+	for j, n := range nodes {
+		x, r := sl.header, 0
+		for i := sl.level - 1; i >= 0; i-- {
+			if rank[i] > r {
+				x, r = update[i], rank[i]
+			}
+			for y := x.level[i].forward; y != nil && sl.lessCount(y.item, n.item, &compares); y = x.level[i].forward {
+				r += x.level[i].span
+				x = y
+				steps++
+			}
+			update[i], rank[i] = x, r
+		}
+		// x is the last node less than n, so n is among the equal items
+		// after it.
+		for y := x.level[0].forward; y != n; y = y.level[0].forward {
+			r++
+		}
+		ranks[j] = r + 1
+	}
+	sl.counters.search(steps)
+	sl.counters.compare(compares)
+}
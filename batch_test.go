@@ -0,0 +1,66 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestRankMany(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	sets := map[string]*ZSet[string, TestRank]{
+		"SkipList": New[string](less),
+		"Compact":  NewWithOptions(less, Options[string, TestRank]{CompactThreshold: 1000}),
+		"BTree":    NewWithOptions(less, Options[string, TestRank]{Backend: BackendBTree, BTreeDegree: 4}),
+	}
+	for name, zs := range sets {
+		for i := 0; i < 500; i++ {
+			// few scores, so that there are many ties.
+			key := strconv.Itoa(i)
+			zs.Add(key, TestRank{member: key, score: rand.Intn(20)})
+		}
+		var keys []string
+		for i := 0; i < 200; i++ {
+			// missing and repeated keys.
+			keys = append(keys, strconv.Itoa(rand.Intn(600)))
+		}
+		for _, reverse := range []bool{false, true} {
+			ranks := zs.RankMany(keys, reverse)
+			for i, key := range keys {
+				if want := zs.Rank(key, reverse); ranks[i] != want {
+					t.Fatal(name, "RankMany error", key, reverse, ranks[i], want)
+				}
+			}
+		}
+		items, found := zs.GetMany(keys)
+		for i, key := range keys {
+			if v, ok := zs.Get(key); items[i] != v || found[i] != ok {
+				t.Fatal(name, "GetMany error", key, items[i], found[i])
+			}
+		}
+		if ranks := zs.RankMany(nil, false); len(ranks) != 0 {
+			t.Fatal(name, "RankMany error", ranks)
+		}
+	}
+}
+
+func BenchmarkRankMany(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		tr := newSet()
+		for _, item := range perm(benchmarkListSize) {
+			tr.Add(item.member, item)
+		}
+		keys := make([]string, 200)
+		for i := range keys {
+			keys[i] = strconv.Itoa(rand.Intn(benchmarkListSize))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.RankMany(keys, false)
+		}
+	})
+}
@@ -0,0 +1,616 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultBTreeDegree is the default maximum number of children of a B-tree
+// node, and of set nodes in a leaf.
+const DefaultBTreeDegree = 64
+
+// btreeNode is a node of a counted B+ tree. Leaves hold the set nodes, inner
+// nodes their children. Every node knows how many set nodes are below it,
+// which gives the ranks, and the first of them, which orders the children.
+type btreeNode[K comparable, T any] struct {
+	count    int
+	first    *node[K, T]
+	parent   *btreeNode[K, T]   // nil for the root
+	slot     int                // leaves only: index in btree.leaves
+	items    []*node[K, T]      // leaves only
+	children []*btreeNode[K, T] // inner nodes only
+}
+
+// btree is a backend which orders the set nodes with a counted B+ tree. The
+// set nodes have a single level, holding the forward link, and in place of the
+// span the slot of their leaf.
+type btree[K comparable, T any] struct {
+	counters counters // reported by Stats
+
+	root       *btreeNode[K, T]
+	height     int // 1 if the root is a leaf
+	degree     int
+	head, tail *node[K, T]
+	freelist   *FreeList[K, T]
+	less       LessFunc[T]
+	// leaves are the leaves by slot, which give the rank of a set node
+	// without comparing it to the items equal to it. freeSlots are the
+	// unused slots.
+	leaves    []*btreeNode[K, T]
+	freeSlots []int
+}
+
+// newBTree creates a B-tree configured by opts.
+func newBTree[K comparable, T any](less LessFunc[T], opts Options[K, T]) *btree[K, T] {
+	opts = opts.withDefaults()
+	freelist := opts.FreeList
+	if freelist == nil {
+		freelist = NewFreeList[K, T](DefaultFreeListSize)
+	}
+	t := &btree[K, T]{
+		height:   1,
+		degree:   opts.BTreeDegree,
+		freelist: freelist,
+		less:     less,
+	}
+	t.root = t.newLeaf()
+	return t
+}
+
+// newLeaf returns a new empty leaf, with a slot.
+func (t *btree[K, T]) newLeaf() *btreeNode[K, T] {
+	b := &btreeNode[K, T]{}
+	if n := len(t.freeSlots); n > 0 {
+		b.slot = t.freeSlots[n-1]
+		t.freeSlots = t.freeSlots[:n-1]
+		t.leaves[b.slot] = b
+	} else {
+		b.slot = len(t.leaves)
+		t.leaves = append(t.leaves, b)
+	}
+	return b
+}
+
+// freeLeaf releases the slot of leaf b, which has left the tree.
+func (t *btree[K, T]) freeLeaf(b *btreeNode[K, T]) {
+	t.leaves[b.slot] = nil
+	t.freeSlots = append(t.freeSlots, b.slot)
+}
+
+func (b *btreeNode[K, T]) leaf() bool {
+	return b.children == nil
+}
+
+func (b *btreeNode[K, T]) size() int {
+	if b.leaf() {
+		return len(b.items)
+	}
+	return len(b.children)
+}
+
+// fix recomputes the count and the first node of b from its content.
+func (b *btreeNode[K, T]) fix() {
+	b.first = nil
+	if b.leaf() {
+		b.count = len(b.items)
+		if len(b.items) > 0 {
+			b.first = b.items[0]
+		}
+		return
+	}
+	b.count = 0
+	for _, c := range b.children {
+		b.count += c.count
+	}
+	b.first = b.children[0].first
+}
+
+func (t *btree[K, T]) len() int {
+	return t.root.count
+}
+
+// lessThan calls the LessFunc of the tree, and counts the call.
+func (t *btree[K, T]) lessThan(a, b T) bool {
+	t.counters.compare(1)
+	return t.less(a, b)
+}
+
+// lessCount calls the LessFunc of the tree, and counts the call in *compares,
+// for the caller to add to the counters once per operation.
+func (t *btree[K, T]) lessCount(a, b T, compares *int) bool {
+	*compares++
+	return t.less(a, b)
+}
+
+// empty returns a new empty B-tree with the same settings.
+func (t *btree[K, T]) empty() backend[K, T] {
+	return newBTree(t.less, Options[K, T]{BTreeDegree: t.degree})
+}
+
+// prefix returns the number of leading nodes for which f returns true. f must
+// return true for a prefix of the nodes, and false for the rest.
+func (t *btree[K, T]) prefix(f func(i T) bool) int {
+	count, steps := 0, 1
+	b := t.root
+	for !b.leaf() {
+		j := sort.Search(len(b.children), func(i int) bool {
+			return !f(b.children[i].first.item)
+		})
+		if j == 0 {
+			t.counters.search(steps)
+			return count
+		}
+		for _, c := range b.children[:j-1] {
+			count += c.count
+		}
+		b = b.children[j-1]
+		steps++
+	}
+	t.counters.search(steps)
+	return count + sort.Search(len(b.items), func(i int) bool {
+		return !f(b.items[i].item)
+	})
+}
+
+// getNodeByRank returns the node of 1-based rank, or nil.
+func (t *btree[K, T]) getNodeByRank(rank int) *node[K, T] {
+	if rank < 1 || rank > t.root.count {
+		return nil
+	}
+	b, i, steps := t.root, rank-1, 1
+	for !b.leaf() {
+		steps++
+		for _, c := range b.children {
+			if i < c.count {
+				b = c
+				break
+			}
+			i -= c.count
+		}
+	}
+	t.counters.search(steps)
+	return b.items[i]
+}
+
+func (t *btree[K, T]) advance(x *node[K, T], rank, target int) *node[K, T] {
+	if x == nil || target-rank > t.degree {
+		return t.getNodeByRank(target)
+	}
+	for ; rank < target; rank++ {
+		x = x.level[0].forward
+	}
+	return x
+}
+
+func (t *btree[K, T]) getMinNode() *node[K, T] {
+	return t.head
+}
+
+func (t *btree[K, T]) getMaxNode() *node[K, T] {
+	return t.tail
+}
+
+func (t *btree[K, T]) findNext(greater func(i T) bool) (*node[K, T], int) {
+	rank := t.prefix(func(i T) bool { return !greater(i) }) + 1
+	if rank > t.root.count {
+		return nil, 0
+	}
+	return t.getNodeByRank(rank), rank
+}
+
+func (t *btree[K, T]) findPrev(less func(i T) bool) (*node[K, T], int) {
+	rank := t.prefix(less)
+	if rank == 0 {
+		return nil, 0
+	}
+	return t.getNodeByRank(rank), rank
+}
+
+func (t *btree[K, T]) rank(n *node[K, T], reverse bool) int {
+	rank := t.nodeRank(n)
+	if rank == 0 {
+		return 0
+	}
+	if reverse {
+		return t.root.count - rank + 1
+	}
+	return rank
+}
+
+// nodeRank returns the 1-based rank of node n, or 0 if it is not in the tree:
+// its index in its leaf, plus the counts of the subtrees before the path from
+// the leaf to the root.
+func (t *btree[K, T]) nodeRank(n *node[K, T]) int {
+	slot := n.level[0].span
+	if slot < 0 || slot >= len(t.leaves) || t.leaves[slot] == nil {
+		return 0
+	}
+	b, i := t.leaves[slot], 0
+	for i < len(b.items) && b.items[i] != n {
+		i++
+	}
+	if i == len(b.items) {
+		return 0
+	}
+	rank, steps := i+1, 1
+	for p := b.parent; p != nil; b, p = p, p.parent {
+		for _, c := range p.children {
+			if c == b {
+				break
+			}
+			rank += c.count
+		}
+		steps++
+	}
+	t.counters.search(steps)
+	return rank
+}
+
+func (t *btree[K, T]) insert(key K, item T) *node[K, T] {
+	compares := 0
+	i := t.prefix(func(y T) bool { return t.lessCount(y, item, &compares) })
+	t.counters.compare(compares)
+	x := t.freelist.newNode(1)
+	x.key, x.item = key, item
+
+	prev := t.getNodeByRank(i)
+	next := t.head
+	if prev != nil {
+		next = prev.level[0].forward
+		prev.level[0].forward = x
+	} else {
+		t.head = x
+	}
+	if next != nil {
+		next.backward = x
+	} else {
+		t.tail = x
+	}
+	x.backward, x.level[0].forward = prev, next
+
+	if right := t.root.insert(t, i, x); right != nil {
+		root := &btreeNode[K, T]{children: []*btreeNode[K, T]{t.root, right}}
+		root.fix()
+		t.root.parent, right.parent = root, root
+		t.root = root
+		t.height++
+	}
+	return x
+}
+
+// insert inserts x at 0-based index i of the subtree of b, in tree t. If b
+// overflows, it is split and the new right half is returned.
+func (b *btreeNode[K, T]) insert(t *btree[K, T], i int, x *node[K, T]) *btreeNode[K, T] {
+	b.count++
+	if b.leaf() {
+		b.items = append(b.items, nil)
+		copy(b.items[i+1:], b.items[i:])
+		b.items[i] = x
+		b.first = b.items[0]
+		x.level[0].span = b.slot
+		if len(b.items) > t.degree {
+			return b.split(t)
+		}
+		return nil
+	}
+	j := 0
+	for ; j < len(b.children)-1 && i > b.children[j].count; j++ {
+		i -= b.children[j].count
+	}
+	if right := b.children[j].insert(t, i, x); right != nil {
+		b.children = append(b.children, nil)
+		copy(b.children[j+2:], b.children[j+1:])
+		b.children[j+1] = right
+		right.parent = b
+	}
+	b.first = b.children[0].first
+	if len(b.children) > t.degree {
+		return b.split(t)
+	}
+	return nil
+}
+
+// split moves the upper half of b to a new node, and returns it.
+func (b *btreeNode[K, T]) split(t *btree[K, T]) *btreeNode[K, T] {
+	var right *btreeNode[K, T]
+	if b.leaf() {
+		right = t.newLeaf()
+		half := len(b.items) / 2
+		right.items = append(make([]*node[K, T], 0, cap(b.items)), b.items[half:]...)
+		for i := half; i < len(b.items); i++ {
+			b.items[i] = nil
+		}
+		b.items = b.items[:half]
+	} else {
+		right = new(btreeNode[K, T])
+		half := len(b.children) / 2
+		right.children = append(make([]*btreeNode[K, T], 0, cap(b.children)), b.children[half:]...)
+		for i := half; i < len(b.children); i++ {
+			b.children[i] = nil
+		}
+		b.children = b.children[:half]
+	}
+	right.parent = b.parent
+	right.adopt(t, right.items, right.children)
+	b.fix()
+	right.fix()
+	return right
+}
+
+// adopt makes b the leaf of items and the parent of children, which have been
+// moved to it.
+func (b *btreeNode[K, T]) adopt(t *btree[K, T], items []*node[K, T], children []*btreeNode[K, T]) {
+	for _, x := range items {
+		x.level[0].span = b.slot
+	}
+	for _, c := range children {
+		c.parent = b
+	}
+}
+
+func (t *btree[K, T]) delete(n *node[K, T]) T {
+	rank := t.rank(n, false)
+	if rank == 0 {
+		var zero T
+		return zero
+	}
+	t.root.remove(t, rank-1)
+	if !t.root.leaf() && len(t.root.children) == 1 {
+		t.root = t.root.children[0]
+		t.root.parent = nil
+		t.height--
+	}
+
+	if n.backward != nil {
+		n.backward.level[0].forward = n.level[0].forward
+	} else {
+		t.head = n.level[0].forward
+	}
+	if n.level[0].forward != nil {
+		n.level[0].forward.backward = n.backward
+	} else {
+		t.tail = n.backward
+	}
+	removeItem := n.item
+	n.backward = nil
+	t.freelist.freeNode(n)
+	return removeItem
+}
+
+// remove removes the node at 0-based index i of the subtree of b, in tree t. A
+// child left with less than degree/2 entries borrows from or merges with a
+// sibling.
+func (b *btreeNode[K, T]) remove(t *btree[K, T], i int) {
+	b.count--
+	if b.leaf() {
+		copy(b.items[i:], b.items[i+1:])
+		b.items[len(b.items)-1] = nil
+		b.items = b.items[:len(b.items)-1]
+		b.first = nil
+		if len(b.items) > 0 {
+			b.first = b.items[0]
+		}
+		return
+	}
+	j := 0
+	for ; i >= b.children[j].count; j++ {
+		i -= b.children[j].count
+	}
+	b.children[j].remove(t, i)
+	if b.children[j].size() < t.degree/2 {
+		b.rebalance(t, j)
+	}
+	b.first = b.children[0].first
+}
+
+// rebalance refills the underfull child j of b from a sibling.
+func (b *btreeNode[K, T]) rebalance(t *btree[K, T], j int) {
+	min := t.degree / 2
+	c := b.children[j]
+	switch {
+	case j > 0 && b.children[j-1].size() > min:
+		left := b.children[j-1]
+		if c.leaf() {
+			c.items = append(c.items, nil)
+			copy(c.items[1:], c.items)
+			c.items[0] = left.items[len(left.items)-1]
+			c.adopt(t, c.items[:1], nil)
+			left.items[len(left.items)-1] = nil
+			left.items = left.items[:len(left.items)-1]
+		} else {
+			c.children = append(c.children, nil)
+			copy(c.children[1:], c.children)
+			c.children[0] = left.children[len(left.children)-1]
+			c.adopt(t, nil, c.children[:1])
+			left.children[len(left.children)-1] = nil
+			left.children = left.children[:len(left.children)-1]
+		}
+		left.fix()
+		c.fix()
+	case j < len(b.children)-1 && b.children[j+1].size() > min:
+		right := b.children[j+1]
+		if c.leaf() {
+			c.items = append(c.items, right.items[0])
+			c.adopt(t, right.items[:1], nil)
+			copy(right.items, right.items[1:])
+			right.items[len(right.items)-1] = nil
+			right.items = right.items[:len(right.items)-1]
+		} else {
+			c.children = append(c.children, right.children[0])
+			c.adopt(t, nil, right.children[:1])
+			copy(right.children, right.children[1:])
+			right.children[len(right.children)-1] = nil
+			right.children = right.children[:len(right.children)-1]
+		}
+		right.fix()
+		c.fix()
+	default:
+		// merge with a sibling, which has at most min entries.
+		if j > 0 {
+			j--
+		}
+		left, right := b.children[j], b.children[j+1]
+		left.adopt(t, right.items, right.children)
+		if right.leaf() {
+			t.freeLeaf(right)
+		}
+		left.items = append(left.items, right.items...)
+		left.children = append(left.children, right.children...)
+		left.fix()
+		copy(b.children[j+1:], b.children[j+2:])
+		b.children[len(b.children)-1] = nil
+		b.children = b.children[:len(b.children)-1]
+	}
+}
+
+func (t *btree[K, T]) updateItem(n *node[K, T], item T) bool {
+	compares := 0
+	fits := (n.level[0].forward == nil || !t.lessCount(n.level[0].forward.item, item, &compares)) &&
+		(n.backward == nil || !t.lessCount(item, n.backward.item, &compares))
+	t.counters.compare(compares)
+	if fits {
+		n.item = item
+	}
+	return fits
+}
+
+// load fills the empty tree with copies of count nodes starting at n. Leaves
+// and inner nodes are filled evenly, with at most degree entries.
+func (t *btree[K, T]) load(n *node[K, T], count int) {
+	if count == 0 {
+		return
+	}
+	items := make([]*node[K, T], count)
+	var prev *node[K, T]
+	for i := range items {
+		x := t.freelist.newNode(1)
+		x.key, x.item = n.key, n.item
+		x.backward = prev
+		if prev != nil {
+			prev.level[0].forward = x
+		}
+		items[i], prev = x, x
+		n = n.level[0].forward
+	}
+	t.head, t.tail = items[0], prev
+
+	groups := (count + t.degree - 1) / t.degree
+	level := make([]*btreeNode[K, T], groups)
+	t.leaves, t.freeSlots = t.leaves[:0], t.freeSlots[:0]
+	for g := range level {
+		b := t.newLeaf()
+		b.items = items[count*g/groups : count*(g+1)/groups : count*(g+1)/groups]
+		b.adopt(t, b.items, nil)
+		b.fix()
+		level[g] = b
+	}
+	t.height = 1
+	for len(level) > 1 {
+		groups := (len(level) + t.degree - 1) / t.degree
+		parents := make([]*btreeNode[K, T], groups)
+		for g := range parents {
+			b := &btreeNode[K, T]{children: level[len(level)*g/groups : len(level)*(g+1)/groups : len(level)*(g+1)/groups]}
+			b.adopt(t, nil, b.children)
+			b.fix()
+			parents[g] = b
+		}
+		level = parents
+		t.height++
+	}
+	t.root = level[0]
+}
+
+func (t *btree[K, T]) stats(s *Stats) {
+	s.Length = t.root.count
+	s.Level = t.height
+	s.LevelNodes = make([]int, t.height)
+	var walk func(b *btreeNode[K, T], h int)
+	walk = func(b *btreeNode[K, T], h int) {
+		s.LevelNodes[h]++
+		for _, c := range b.children {
+			walk(c, h-1)
+		}
+	}
+	walk(t.root, t.height-1)
+	t.counters.load(s)
+	t.freelist.stats(s)
+}
+
+func (t *btree[K, T]) validate() (map[*node[K, T]]int, error) {
+	ranks := map[*node[K, T]]int{}
+	var prev *node[K, T]
+	leaves := 0
+	var walk func(b *btreeNode[K, T], h int) error
+	walk = func(b *btreeNode[K, T], h int) error {
+		if b != t.root && (b.size() < t.degree/2 || b.size() > t.degree) {
+			return fmt.Errorf("zset: tree node of height %d has %d entries", h, b.size())
+		}
+		if b.leaf() != (h == 1) {
+			return fmt.Errorf("zset: tree leaf at height %d", h)
+		}
+		count := len(ranks)
+		for _, c := range b.children {
+			if c.parent != b {
+				return fmt.Errorf("zset: wrong parent of tree node of height %d", h-1)
+			}
+		}
+		if b.leaf() && (b.slot >= len(t.leaves) || t.leaves[b.slot] != b) {
+			return fmt.Errorf("zset: tree leaf has a wrong slot")
+		}
+		if b.leaf() {
+			leaves++
+		}
+		for _, x := range b.items {
+			if x.level[0].span != b.slot {
+				return fmt.Errorf("zset: wrong leaf of node at rank %d", len(ranks)+1)
+			}
+			if _, ok := ranks[x]; ok {
+				return fmt.Errorf("zset: node at rank %d appears twice", ranks[x])
+			}
+			rank := len(ranks) + 1
+			ranks[x] = rank
+			if len(x.level) != 1 || x.backward != prev {
+				return fmt.Errorf("zset: wrong links at rank %d", rank)
+			}
+			if prev != nil {
+				if prev.level[0].forward != x {
+					return fmt.Errorf("zset: wrong forward link at rank %d", rank-1)
+				}
+				if t.less(x.item, prev.item) {
+					return fmt.Errorf("zset: node at rank %d is less than its predecessor", rank)
+				}
+			}
+			prev = x
+		}
+		for _, c := range b.children {
+			if err := walk(c, h-1); err != nil {
+				return err
+			}
+		}
+		if b.count != len(ranks)-count {
+			return fmt.Errorf("zset: tree node of height %d counts %d nodes, has %d", h, b.count, len(ranks)-count)
+		}
+		if b.count > 0 && ranks[b.first] != count+1 || b.count == 0 && b.first != nil {
+			return fmt.Errorf("zset: wrong first node of tree node of height %d", h)
+		}
+		return nil
+	}
+	if t.root.parent != nil {
+		return nil, fmt.Errorf("zset: tree root has a parent")
+	}
+	if !t.root.leaf() && len(t.root.children) < 2 {
+		return nil, fmt.Errorf("zset: tree root has %d children", len(t.root.children))
+	}
+	if err := walk(t.root, t.height); err != nil {
+		return nil, err
+	}
+	if t.head != t.root.first || t.tail != prev || (prev != nil && prev.level[0].forward != nil) {
+		return nil, fmt.Errorf("zset: wrong head or tail")
+	}
+	if len(t.leaves)-len(t.freeSlots) != leaves {
+		return nil, fmt.Errorf("zset: %d leaf slots used, %d leaves in the tree", len(t.leaves)-len(t.freeSlots), leaves)
+	}
+	return ranks, nil
+}
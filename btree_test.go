@@ -0,0 +1,160 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestBTree(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	for _, degree := range []int{4, 5, DefaultBTreeDegree} {
+		zs := NewWithOptions(less, Options[string, TestRank]{Backend: BackendBTree, BTreeDegree: degree})
+		ref := New[string](less)
+		for i := 0; i < 5000; i++ {
+			key := strconv.Itoa(rand.Intn(500))
+			switch rand.Intn(3) {
+			case 0:
+				if zs.Remove(key) != ref.Remove(key) {
+					t.Fatal("Remove error", key)
+				}
+			default:
+				// few scores, so that there are many ties.
+				v := TestRank{member: key, score: rand.Intn(50)}
+				zs.Add(key, v)
+				ref.Add(key, v)
+			}
+			if err := zs.Validate(); err != nil {
+				t.Fatal(degree, i, err)
+			}
+		}
+		if zs.Length() != ref.Length() {
+			t.Fatal("Length error", zs.Length(), ref.Length())
+		}
+
+		// the skip list ranks ties by item, so only compare the items.
+		var got, want []TestRank
+		zs.Range(0, -1, true, func(v TestRank, _ int) bool {
+			got = append(got, v)
+			return true
+		})
+		ref.Range(0, -1, true, func(v TestRank, _ int) bool {
+			want = append(want, v)
+			return true
+		})
+		for i := range want {
+			if got[i].score != want[i].score {
+				t.Fatal("Range error", i, got[i], want[i])
+			}
+		}
+		for i, v := range got {
+			if rank := zs.Rank(v.member, true); rank != i+1 {
+				t.Fatal("Rank error", v, rank, i+1)
+			}
+		}
+
+		store := zs.RangeByScoreStore(func(v TestRank) bool { return v.score >= 10 }, nil)
+		if _, ok := store.ord.(*btree[string, TestRank]); !ok {
+			t.Error("RangeByScoreStore changed the backend")
+		}
+		if err := store.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, v := range got {
+			if v.score >= 10 {
+				n++
+			}
+		}
+		if store.Length() != n {
+			t.Error("RangeByScoreStore length error", store.Length(), n)
+		}
+
+		s := zs.Stats()
+		if s.Length != zs.Length() || s.Level != len(s.LevelNodes) || s.LevelNodes[s.Level-1] != 1 {
+			t.Error("Stats error", s)
+		}
+
+		for _, v := range perm(500) {
+			zs.Remove(v.member)
+		}
+		if err := zs.Validate(); err != nil || zs.Length() != 0 || zs.Stats().Level != 1 {
+			t.Fatal("remove all error", err, zs.Length())
+		}
+	}
+}
+
+// TestBTreeTies checks that ranks among many equal items come from the leaf of
+// a node rather than from walking the items equal to it.
+func TestBTreeTies(t *testing.T) {
+	zs := NewWithOptions(func(a, b TestRank) bool {
+		return a.score < b.score
+	}, Options[string, TestRank]{Backend: BackendBTree, BTreeDegree: 4})
+	const n = 10000
+	for i := 0; i < n; i++ {
+		zs.Add(strconv.Itoa(i), TestRank{member: strconv.Itoa(i), score: i % 2})
+	}
+	var keys []string
+	zs.RangeWithKeys(0, -1, false, func(key string, _ TestRank, _ int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	before := zs.Stats()
+	for i, key := range keys {
+		if r := zs.Rank(key, false); r != i+1 {
+			t.Fatal("Rank error", key, r, i+1)
+		}
+	}
+	s := zs.Stats()
+	if avg := float64(s.SearchSteps-before.SearchSteps) / n; avg > float64(s.Level) {
+		t.Error("too many steps per Rank among ties", avg, s.Level)
+	}
+	for i := 0; i < n; i += 3 {
+		zs.Remove(strconv.Itoa(i))
+		if i%300 == 0 {
+			if err := zs.Validate(); err != nil {
+				t.Fatal(i, err)
+			}
+		}
+	}
+}
+
+func TestBTreeCorrupted(t *testing.T) {
+	newSet := func() *ZSet[string, TestRank] {
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{Backend: BackendBTree, BTreeDegree: 4})
+		for _, v := range perm(100) {
+			zs.Add(v.member, v)
+		}
+		return zs
+	}
+	corruptions := map[string]func(zs *ZSet[string, TestRank]){
+		"order": func(zs *ZSet[string, TestRank]) {
+			zs.dict["50"].item.score = 1000
+		},
+		"count": func(zs *ZSet[string, TestRank]) {
+			zs.ord.(*btree[string, TestRank]).root.count++
+		},
+		"backward": func(zs *ZSet[string, TestRank]) {
+			zs.dict["50"].backward = nil
+		},
+		"tail": func(zs *ZSet[string, TestRank]) {
+			zs.ord.(*btree[string, TestRank]).tail = zs.dict["50"]
+		},
+		"first": func(zs *ZSet[string, TestRank]) {
+			zs.ord.(*btree[string, TestRank]).root.first = zs.dict["50"]
+		},
+	}
+	for name, corrupt := range corruptions {
+		zs := newSet()
+		corrupt(zs)
+		if err := zs.Validate(); err == nil {
+			t.Error("corruption not detected", name)
+		}
+	}
+}
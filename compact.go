@@ -0,0 +1,61 @@
+//go:build go1.18
+
+package zset
+
+// DefaultCompactThreshold is a good Options.CompactThreshold for most sets.
+// Below it, a linear key lookup is about as fast as a map.
+const DefaultCompactThreshold = 64
+
+// A compact set has no dict, and its skip list has a single level, a one
+// level header, no random generator and no free list unless one is shared
+// through Options. Keys are found by walking the list. When it grows beyond
+// its threshold, it is promoted to a dict and a full skip list, and stays so.
+
+// newCompactSkipList creates a single level skip list for a compact set.
+func newCompactSkipList[K comparable, T any](less LessFunc[T], opts Options[K, T]) *skipList[K, T] {
+	return &skipList[K, T]{
+		level: 1,
+		header: &node[K, T]{
+			level: make([]skipListLevel[K, T], 1),
+		},
+		maxLevel: 1,
+		p:        opts.P,
+		freelist: opts.FreeList,
+		less:     less,
+	}
+}
+
+// lookup returns the node of key, or nil if not exist.
+func (zs *ZSet[K, T]) lookup(key K) *node[K, T] {
+	if zs.compact == nil {
+		return zs.dict[key]
+	}
+	for x := zs.sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.key == key {
+			return x
+		}
+	}
+	return nil
+}
+
+// promote converts a compact set to a dict and a full skip list.
+func (zs *ZSet[K, T]) promote() {
+	old := zs.sl
+	sl := newSkipList(old.less, *zs.compact)
+	sl.load(old.header.level[0].forward, old.length)
+	sl.counters = old.counters
+	for x := old.header.level[0].forward; x != nil; {
+		next := x.level[0].forward
+		sl.freelist.freeNode(x)
+		x = next
+	}
+	capacity := zs.compact.InitialCapacity
+	if capacity < sl.length {
+		capacity = sl.length
+	}
+	zs.dict = make(map[K]*node[K, T], capacity)
+	for x := sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		zs.dict[x.key] = x
+	}
+	zs.ord, zs.sl, zs.compact = sl, sl, nil
+}
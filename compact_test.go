@@ -0,0 +1,129 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	zs := NewWithOptions(less, Options[string, TestRank]{CompactThreshold: 10})
+	ref := New[string](less)
+	var events []EventType
+	zs.Subscribe(func(e Event[string, TestRank]) {
+		events = append(events, e.Type)
+	})
+	items := perm(20)
+	for i, v := range items[:10] {
+		zs.Add(v.member, v)
+		ref.Add(v.member, v)
+		if zs.Rank(v.member, false) == 0 || zs.Length() != i+1 {
+			t.Fatal("compact add error", v)
+		}
+	}
+	if zs.compact == nil || zs.dict != nil || zs.Stats().MaxLevel != 1 {
+		t.Fatal("set promoted before its threshold")
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	updated := TestRank{member: items[0].member, score: 100}
+	zs.Add(updated.member, updated)
+	ref.Add(updated.member, updated)
+	zs.Remove(items[1].member)
+	ref.Remove(items[1].member)
+	if v, ok := zs.Get(updated.member); !ok || v != updated || zs.Rank(updated.member, true) != 1 {
+		t.Error("compact update error", v)
+	}
+	if _, ok := zs.Get(items[1].member); ok {
+		t.Error("compact remove error")
+	}
+
+	for _, v := range items[10:] {
+		zs.Add(v.member, v)
+		ref.Add(v.member, v)
+	}
+	if zs.compact != nil || len(zs.dict) != 19 || zs.Stats().MaxLevel != DefaultMaxLevel {
+		t.Fatal("set not promoted")
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ref.Range(0, -1, false, func(v TestRank, rank int) bool {
+		if zs.Rank(v.member, false) != rank {
+			t.Error("promoted set rank error", v, rank)
+		}
+		return true
+	})
+	if len(events) != 22 {
+		t.Error("promotion lost events", len(events))
+	}
+
+	// removing elements does not demote the set.
+	for _, v := range items {
+		zs.Remove(v.member)
+	}
+	if zs.compact != nil || zs.Length() != 0 {
+		t.Error("set demoted")
+	}
+}
+
+func TestCompactAllocs(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	items := perm(DefaultCompactThreshold)
+	build := func(opts Options[string, TestRank]) []TestRank {
+		zs := NewWithOptions(less, opts)
+		for _, v := range items {
+			zs.Add(v.member, v)
+		}
+		var out []TestRank
+		zs.Range(0, -1, false, func(v TestRank, _ int) bool {
+			out = append(out, v)
+			return true
+		})
+		return out
+	}
+	compact := Options[string, TestRank]{CompactThreshold: DefaultCompactThreshold}
+	if !reflect.DeepEqual(build(compact), build(Options[string, TestRank]{})) {
+		t.Fatal("compact set ordered differently")
+	}
+	compactAllocs := testing.AllocsPerRun(10, func() { NewWithOptions(less, compact) })
+	allocs := testing.AllocsPerRun(10, func() { New[string](less) })
+	if compactAllocs >= allocs {
+		t.Error("compact set allocates as much as a default set", compactAllocs, allocs)
+	}
+}
+
+func BenchmarkAddCompact(b *testing.B) {
+	insertP := perm(DefaultCompactThreshold)
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		zs := NewWithOptions(less, Options[string, TestRank]{CompactThreshold: DefaultCompactThreshold})
+		for _, item := range insertP {
+			zs.Add(item.member, item)
+		}
+	}
+}
+
+func BenchmarkAddSmall(b *testing.B) {
+	insertP := perm(DefaultCompactThreshold)
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		zs := New[string](less)
+		for _, item := range insertP {
+			zs.Add(item.member, item)
+		}
+	}
+}
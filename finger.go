@@ -0,0 +1,163 @@
+//go:build go1.18
+
+package zset
+
+// search fills update with the last node before item at every level, and rank
+// with their ranks. If there is a finger, it climbs from it, otherwise it
+// descends from the header.
+func (sl *skipList[K, T]) search(item T, update []*node[K, T], rank []int) {
+	compares := 0
+	if len(sl.finger) > 0 {
+		sl.climb(func(i T) bool { return sl.lessCount(i, item, &compares) }, update, rank)
+		sl.counters.compare(compares)
+		return
+	}
+	x, r, steps := sl.header, 0, 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for y := x.level[i].forward; y != nil && sl.lessCount(y.item, item, &compares); y = x.level[i].forward {
+			r += x.level[i].span
+			x = y
+			steps++
+		}
+		update[i], rank[i] = x, r
+	}
+	sl.counters.search(steps)
+	sl.counters.compare(compares)
+}
+
+// climb fills update with the last node for which before returns true at every
+// level, and rank with their ranks. before must return true for a prefix of
+// the list. It starts from the finger: it climbs the finger up to the first
+// level where the finger is before the position and its forward link is not,
+// and descends from there. So it costs O(log d) calls of before, where d is the
+// distance between the finger and the position. It does not move the finger.
+func (sl *skipList[K, T]) climb(before func(i T) bool, update []*node[K, T], rank []int) {
+	// going up, the finger only moves back and its forward links only move
+	// ahead, so a condition which holds at a level holds above it.
+	k, fingerBefore, forwardAfter := 0, false, false
+	for ; k < sl.level; k++ {
+		f := sl.finger[k]
+		if !fingerBefore {
+			fingerBefore = f == sl.header || before(f.item)
+		}
+		if fingerBefore && !forwardAfter {
+			y := f.level[k].forward
+			forwardAfter = y == nil || !before(y.item)
+		}
+		if fingerBefore && forwardAfter {
+			break
+		}
+	}
+	x, r, steps := sl.header, 0, 0
+	if k < sl.level {
+		// the finger is the search path from level k.
+		copy(update[k:sl.level], sl.finger[k:sl.level])
+		copy(rank[k:sl.level], sl.fingerRank[k:sl.level])
+		x, r = sl.finger[k], sl.fingerRank[k]
+	}
+	for i := k - 1; i >= 0; i-- {
+		// skip to the finger if it is between x and the position.
+		if sl.fingerRank[i] > r && before(sl.finger[i].item) {
+			x, r = sl.finger[i], sl.fingerRank[i]
+		}
+		for y := x.level[i].forward; y != nil && before(y.item); y = x.level[i].forward {
+			r += x.level[i].span
+			x = y
+			steps++
+		}
+		update[i], rank[i] = x, r
+	}
+	sl.counters.search(steps)
+}
+
+// climbLast returns the last node for which before returns true, or the
+// header, and its rank, by climbing from the finger.
+func (sl *skipList[K, T]) climbLast(before func(i T) bool) (*node[K, T], int) {
+	var update [DefaultMaxLevel]*node[K, T]
+	var rank [DefaultMaxLevel]int
+	sl.climb(before, update[:], rank[:])
+	return update[0], rank[0]
+}
+
+// setFinger sets the finger to a search path.
+func (sl *skipList[K, T]) setFinger(update []*node[K, T], rank []int) {
+	if cap(sl.finger) == 0 {
+		sl.finger = make([]*node[K, T], sl.maxLevel)
+		sl.fingerRank = make([]int, sl.maxLevel)
+	}
+	sl.finger = sl.finger[:sl.maxLevel]
+	copy(sl.finger[:sl.level], update[:sl.level])
+	copy(sl.fingerRank[:sl.level], rank[:sl.level])
+}
+
+// dropFinger forgets the finger, keeping its storage.
+func (sl *skipList[K, T]) dropFinger() {
+	sl.finger = sl.finger[:0]
+}
+
+// searchRank fills update with the last node of rank at most target at every
+// level, and rank with their ranks, without comparing items.
+func (sl *skipList[K, T]) searchRank(target int, update []*node[K, T], rank []int) {
+	x, r, steps := sl.header, 0, 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r+x.level[i].span <= target {
+			r += x.level[i].span
+			x = x.level[i].forward
+			steps++
+		}
+		update[i], rank[i] = x, r
+	}
+	sl.counters.search(steps)
+}
+
+// hint moves the finger just before node n, without comparing items, as
+// insert puts an item before the items equal to it. Without Options.Finger,
+// the finger only serves the next insert.
+func (sl *skipList[K, T]) hint(n *node[K, T]) {
+	var update [DefaultMaxLevel]*node[K, T]
+	var rank [DefaultMaxLevel]int
+	sl.searchRank(sl.nodeRank(n)-1, update[:], rank[:])
+	sl.setFinger(update[:], rank[:])
+}
+
+// unlinkFinger keeps the finger valid after the node x of rank xRank has been
+// removed, update being the predecessors of x. The finger stays at the same
+// position: where it went through x, it goes through the predecessor of x.
+func (sl *skipList[K, T]) unlinkFinger(x *node[K, T], xRank int, update []*node[K, T], rank []int) {
+	for i := 0; i < len(sl.finger) && i < sl.level; i++ {
+		if sl.finger[i] == x {
+			sl.finger[i], sl.fingerRank[i] = update[i], rank[i]
+		} else if sl.fingerRank[i] > xRank {
+			sl.fingerRank[i]--
+		}
+	}
+}
+
+// nodeRank returns the 1-based rank of node n, without comparing items: the
+// top level links from n to the end span the nodes after n.
+func (sl *skipList[K, T]) nodeRank(n *node[K, T]) int {
+	rank := sl.length
+	for x := n; x != nil; {
+		top := len(x.level) - 1
+		rank -= x.level[top].span
+		x = x.level[top].forward
+	}
+	return rank
+}
+
+// hint does nothing, as B-tree searches always start from the root.
+func (t *btree[K, T]) hint(n *node[K, T]) {}
+
+// AddHint is like Add, but starts searching for the position of item from the
+// element of key near, if it exists. With the skip list backend, the search
+// costs O(log d) comparisons, where d is the distance between item and near.
+//
+// With Options.Finger, Add already starts from the position of the previous
+// insert, so AddHint is useful when item is close to near but far from the
+// previous insert.
+func (zs *ZSet[K, T]) AddHint(key K, item T, near K) (removeItem T) {
+	if n := zs.lookup(near); n != nil {
+		zs.ord.hint(n)
+	}
+	return zs.Add(key, item)
+}
@@ -0,0 +1,179 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// checkFinger checks that the finger is the search path of a position.
+func checkFinger(t *testing.T, sl *skipList[string, TestRank]) {
+	t.Helper()
+	ranks, err := sl.validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sl.finger) == 0 {
+		return
+	}
+	pos := sl.fingerRank[0]
+	for i := 0; i < sl.level; i++ {
+		f := sl.finger[i]
+		if rank, ok := ranks[f]; !ok || rank != sl.fingerRank[i] || len(f.level) <= i {
+			t.Fatalf("finger of level %d has rank %d, want %d", i, sl.fingerRank[i], rank)
+		}
+		if y := f.level[i].forward; sl.fingerRank[i] > pos || y != nil && ranks[y] <= pos {
+			t.Fatalf("finger of level %d is not the last node before rank %d", i, pos)
+		}
+	}
+}
+
+func TestFinger(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		if a.score == b.score {
+			return a.member < b.member
+		}
+		return a.score < b.score
+	}
+	zs := NewWithOptions(less, Options[string, TestRank]{Finger: true})
+	ref := New[string](less)
+	for i := 0; i < 3000; i++ {
+		key := strconv.Itoa(rand.Intn(200))
+		switch rand.Intn(5) {
+		case 0:
+			zs.Remove(key)
+			ref.Remove(key)
+		case 1:
+			if rank := zs.Rank(key, false); rank > 0 && zs.sl.getNodeByRank(rank).key != key {
+				t.Fatal("Rank error", key, rank)
+			}
+		case 2:
+			// lookups start from the finger.
+			score := rand.Intn(32)
+			next := func(i TestRank) bool { return i.score >= score }
+			prev := func(i TestRank) bool { return i.score < score }
+			v1, r1 := zs.FindNext(next)
+			v2, r2 := ref.FindNext(next)
+			v3, r3 := zs.FindPrev(prev)
+			v4, r4 := ref.FindPrev(prev)
+			if v1 != v2 || r1 != r2 || v3 != v4 || r3 != r4 {
+				t.Fatal("lookup error", score, v1, r1, v2, r2, v3, r3, v4, r4)
+			}
+		case 3:
+			near := strconv.Itoa(rand.Intn(200))
+			v := TestRank{member: key, score: rand.Intn(30)}
+			zs.AddHint(key, v, near)
+			ref.Add(key, v)
+		default:
+			v := TestRank{member: key, score: rand.Intn(30)}
+			zs.Add(key, v)
+			ref.Add(key, v)
+		}
+		checkFinger(t, zs.sl)
+	}
+}
+
+func TestFingerOptIn(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(1000) {
+		zs.Add(v.member, v)
+		if len(zs.sl.finger) != 0 {
+			t.Fatal("Add kept a finger without Options.Finger")
+		}
+	}
+	// a hint serves the next insert only.
+	zs.AddHint("x", TestRank{member: "x", score: 500}, "500")
+	if len(zs.sl.finger) != 0 {
+		t.Fatal("AddHint kept a finger without Options.Finger")
+	}
+	if rank := zs.Rank("x", false); rank != 501 {
+		t.Error("AddHint rank error", rank)
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFingerLocality(t *testing.T) {
+	// a fixed layout, as the cost near the finger depends on the levels of
+	// the nodes there.
+	zs := NewWithOptions(func(a, b TestRank) bool {
+		return a.score < b.score
+	}, Options[string, TestRank]{Finger: true, Seed: 1})
+	const n = 10000
+	for i := 0; i < n; i++ {
+		zs.Add(strconv.Itoa(i), TestRank{member: strconv.Itoa(i), score: i})
+	}
+	// a sliding window: append at the tail and trim the head.
+	before := zs.sl.counters.compares
+	for i := n; i < 2*n; i++ {
+		zs.Add(strconv.Itoa(i), TestRank{member: strconv.Itoa(i), score: i})
+		zs.Remove(strconv.Itoa(i - n))
+	}
+	if avg := float64(zs.sl.counters.compares-before) / n; avg > 12 {
+		t.Error("too many compares per append and trim", avg)
+	}
+
+	// lookups near the last insert.
+	steps := zs.Stats().SearchSteps
+	for i := 0; i < 100; i++ {
+		score := 2*n - 1 - i%10
+		if v, rank := zs.FindNext(func(i TestRank) bool { return i.score >= score }); v.score != score || rank != n-i%10 {
+			t.Fatal("FindNext error", v, rank)
+		}
+	}
+	if avg := float64(zs.Stats().SearchSteps-steps) / 100; avg > 12 {
+		t.Error("too many steps per lookup near the finger", avg)
+	}
+
+	// fill the gaps next to an element far from the previous operation.
+	for i := 0; i < 100; i++ {
+		zs.Add(strconv.Itoa(3*n+i), TestRank{member: strconv.Itoa(3*n + i), score: 2*n - 1 + i})
+		before := zs.sl.counters.compares
+		zs.AddHint(strconv.Itoa(-i), TestRank{member: strconv.Itoa(-i), score: n + n/2}, strconv.Itoa(n+n/2))
+		if c := zs.sl.counters.compares - before; c > 8 {
+			t.Error("too many compares for a hinted add", c)
+		}
+		zs.Remove(strconv.Itoa(-i))
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkAddHint(b *testing.B) {
+	insertP := perm(benchmarkListSize)
+	tr := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, item := range insertP {
+		tr.Add(item.member, item)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		near := insertP[i%benchmarkListSize]
+		item := TestRank{member: "hint", score: near.score}
+		tr.AddHint(item.member, item, near.member)
+		tr.Remove(item.member)
+	}
+}
+
+func BenchmarkAddAppend(b *testing.B) {
+	tr := NewWithOptions(func(a, b TestRank) bool {
+		return a.score < b.score
+	}, Options[string, TestRank]{Finger: true})
+	keys := make([]string, benchmarkListSize)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%benchmarkListSize]
+		tr.Remove(key)
+		tr.Add(key, TestRank{member: key, score: i})
+	}
+}
@@ -0,0 +1,79 @@
+//go:build go1.18
+
+package zset
+
+// AddFlag is a condition of AddWithFlags, like the flags of ZADD in redis.
+// Flags are combined with |.
+type AddFlag int
+
+const (
+	// AddNX only adds new elements, and never updates existing ones.
+	AddNX AddFlag = 1 << iota
+	// AddXX only updates existing elements, and never adds new ones.
+	AddXX
+	// AddGT only updates an existing element if the new item is greater
+	// than its item under the LessFunc. It does not prevent adding new
+	// elements.
+	AddGT
+	// AddLT only updates an existing element if the new item is less than
+	// its item under the LessFunc. It does not prevent adding new elements.
+	AddLT
+)
+
+// AddResult is the result of AddWithFlags. Exactly one of Added, Updated and
+// Unchanged is true.
+type AddResult[T any] struct {
+	// Added reports that the key was new and its element was added.
+	Added bool
+	// Updated reports that the element of the key got the new item.
+	Updated bool
+	// Unchanged reports that the flags prevented the change.
+	Unchanged bool
+	// Existed reports that the key existed before the call, in which case
+	// Previous is its item.
+	Existed  bool
+	Previous T
+	// Rank is the 1-based rank of the key after the call, or 0 if not
+	// exist.
+	Rank int
+	// RankDelta is Rank minus the rank of the key before the call, if
+	// Updated.
+	RankDelta int
+}
+
+// AddWithFlags adds or updates the element of key like Add, if the flags
+// allow it, and reports what it did. It panics if AddNX is combined with
+// another flag, or AddGT with AddLT, as redis rejects them.
+func (zs *ZSet[K, T]) AddWithFlags(key K, item T, flags AddFlag) (r AddResult[T]) {
+	if flags&AddNX != 0 && flags&(AddXX|AddGT|AddLT) != 0 {
+		panic("zset: AddNX is not compatible with AddXX, AddGT and AddLT")
+	}
+	if flags&AddGT != 0 && flags&AddLT != 0 {
+		panic("zset: AddGT is not compatible with AddLT")
+	}
+	n := zs.lookup(key)
+	if n == nil {
+		if flags&AddXX != 0 {
+			r.Unchanged = true
+			return
+		}
+		zs.Add(key, item)
+		r.Added = true
+		r.Rank = zs.ord.rank(zs.lookup(key), false)
+		return
+	}
+	r.Existed, r.Previous = true, n.item
+	oldRank := zs.ord.rank(n, false)
+	if flags&AddNX != 0 ||
+		flags&AddGT != 0 && !zs.ord.lessThan(n.item, item) ||
+		flags&AddLT != 0 && !zs.ord.lessThan(item, n.item) {
+		r.Unchanged = true
+		r.Rank = oldRank
+		return
+	}
+	zs.Add(key, item)
+	r.Updated = true
+	r.Rank = zs.ord.rank(zs.lookup(key), false)
+	r.RankDelta = r.Rank - oldRank
+	return
+}
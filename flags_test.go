@@ -0,0 +1,77 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"testing"
+)
+
+func TestAddWithFlags(t *testing.T) {
+	newSet := func() *ZSet[string, TestRank] {
+		zs := New[string, TestRank](func(a, b TestRank) bool {
+			if a.score == b.score {
+				return a.member < b.member
+			}
+			return a.score < b.score
+		})
+		for _, v := range rang(10) {
+			zs.Add(v.member, v)
+		}
+		return zs
+	}
+	tests := []struct {
+		name  string
+		key   string
+		score int
+		flags AddFlag
+		want  AddResult[TestRank]
+	}{
+		{"add", "a", 5, 0, AddResult[TestRank]{Added: true, Rank: 7}},
+		{"update", "3", 20, 0, AddResult[TestRank]{Updated: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 10, RankDelta: 6}},
+		{"update in place", "3", 3, 0, AddResult[TestRank]{Updated: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 4}},
+		{"nx new", "a", 5, AddNX, AddResult[TestRank]{Added: true, Rank: 7}},
+		{"nx existing", "3", 20, AddNX, AddResult[TestRank]{Unchanged: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 4}},
+		{"xx new", "a", 5, AddXX, AddResult[TestRank]{Unchanged: true}},
+		{"xx existing", "3", 0, AddXX, AddResult[TestRank]{Updated: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 2, RankDelta: -2}},
+		{"gt greater", "3", 20, AddGT, AddResult[TestRank]{Updated: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 10, RankDelta: 6}},
+		{"gt less", "3", 0, AddGT, AddResult[TestRank]{Unchanged: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 4}},
+		{"gt equal", "3", 3, AddGT, AddResult[TestRank]{Unchanged: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 4}},
+		{"gt new", "a", 5, AddGT, AddResult[TestRank]{Added: true, Rank: 7}},
+		{"lt less", "3", 0, AddLT, AddResult[TestRank]{Updated: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 2, RankDelta: -2}},
+		{"lt greater", "3", 20, AddLT, AddResult[TestRank]{Unchanged: true, Existed: true, Previous: TestRank{"3", 3}, Rank: 4}},
+		{"xx gt", "a", 5, AddXX | AddGT, AddResult[TestRank]{Unchanged: true}},
+	}
+	for _, tt := range tests {
+		zs := newSet()
+		var events []Event[string, TestRank]
+		zs.Subscribe(func(e Event[string, TestRank]) {
+			events = append(events, e)
+		})
+		got := zs.AddWithFlags(tt.key, TestRank{member: tt.key, score: tt.score}, tt.flags)
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if (len(events) == 0) != got.Unchanged {
+			t.Errorf("%s: events %v", tt.name, events)
+		}
+		v, ok := zs.Get(tt.key)
+		if got.Unchanged && (ok != got.Existed || ok && v != got.Previous) ||
+			!got.Unchanged && v.score != tt.score {
+			t.Errorf("%s: item %v", tt.name, v)
+		}
+		if err := zs.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, flags := range []AddFlag{AddNX | AddXX, AddNX | AddGT, AddGT | AddLT} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic", flags)
+				}
+			}()
+			newSet().AddWithFlags("a", TestRank{}, flags)
+		}()
+	}
+}
@@ -0,0 +1,35 @@
+//go:build go1.18
+
+package zset_test
+
+import (
+	"testing"
+
+	"github.com/liwnn/zset"
+	"github.com/liwnn/zset/zsettest"
+)
+
+func FuzzZSet(f *testing.F) {
+	f.Add(false, []byte{0, 1, 2, 0, 2, 2, 4, 1, 0, 5, 0, 255})
+	f.Add(false, []byte{1, 3, 7, 1, 4, 7, 1, 5, 7, 130, 4, 0, 6, 0, 20, 7, 4, 7, 8, 4, 7})
+	f.Add(true, []byte{0, 1, 2, 0, 2, 2, 0, 3, 2, 4, 1, 0, 2, 2, 0, 130, 3, 0})
+	f.Fuzz(func(t *testing.T, ties bool, ops []byte) {
+		less := zsettest.Less
+		if ties {
+			less = zsettest.LessScore
+		}
+		zsettest.Exec(t, zset.New[string](less), less, ops)
+	})
+}
+
+func FuzzBTree(f *testing.F) {
+	f.Add(false, []byte{0, 1, 2, 0, 2, 2, 4, 1, 0, 5, 0, 255})
+	f.Add(true, []byte{0, 1, 2, 0, 2, 2, 0, 3, 2, 4, 1, 0, 2, 2, 0, 130, 3, 0})
+	f.Fuzz(func(t *testing.T, ties bool, ops []byte) {
+		less := zsettest.Less
+		if ties {
+			less = zsettest.LessScore
+		}
+		zsettest.Exec(t, zset.NewWithOptions(less, zset.Options[string, zsettest.Item]{Backend: zset.BackendBTree, BTreeDegree: 4}), less, ops)
+	})
+}
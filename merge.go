@@ -0,0 +1,206 @@
+//go:build go1.18
+
+package zset
+
+import "container/heap"
+
+// MergePolicy selects how MergeRange treats a key found in several sets.
+type MergePolicy int
+
+const (
+	// MergeAll visits the elements of a key in every set.
+	MergeAll MergePolicy = iota
+	// MergeFirst only visits the element of a key that comes first in the
+	// merged order.
+	MergeFirst
+	// MergeLast only visits the element of a key that comes last in the
+	// merged order.
+	MergeLast
+)
+
+// mergeCursor is the next element of one set in a merge.
+type mergeCursor[K comparable, T any] struct {
+	n   *node[K, T]
+	set int
+}
+
+// mergeHeap orders the cursors of a merge by item, then by set index.
+type mergeHeap[K comparable, T any] struct {
+	cursors []mergeCursor[K, T]
+	less    LessFunc[T]
+}
+
+func (h *mergeHeap[K, T]) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap[K, T]) Less(i, j int) bool {
+	return mergeBefore(h.less, h.cursors[i].n.item, h.cursors[i].set, h.cursors[j].n.item, h.cursors[j].set)
+}
+
+func (h *mergeHeap[K, T]) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h *mergeHeap[K, T]) Push(x any) {
+	h.cursors = append(h.cursors, x.(mergeCursor[K, T]))
+}
+
+func (h *mergeHeap[K, T]) Pop() any {
+	c := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return c
+}
+
+// mergeBefore reports whether item a of set i comes before item b of set j in
+// the merged order.
+func mergeBefore[T any](less LessFunc[T], a T, i int, b T, j int) bool {
+	if less(a, b) {
+		return true
+	}
+	return !less(b, a) && i < j
+}
+
+// MergeRange calls the iterator for every element with in index range [start,
+// end] of the merged order of sets, until iterator return false. The rank
+// passed to the iterator is the 1-based rank in the merged order. Elements are
+// ordered by less, which must agree with the order of every set, then by the
+// index of their set. Negative indexes count from the end, as in Range.
+//
+// With MergeAll, the sets are walked from close to start: the elements before
+// it are skipped by rank, in O(k^2 log start) rank lookups for k sets. Otherwise
+// the elements before start are visited to resolve the keys found in several
+// sets, which costs a lookup in every set per element, and so does counting
+// the elements for a negative index.
+func MergeRange[K comparable, T any](sets []*ZSet[K, T], less LessFunc[T], policy MergePolicy, start, end int, iterator ItemIterator[T]) {
+	if start < 0 || end < 0 {
+		length := mergeLen(sets, policy)
+		if start < 0 {
+			start = length + start
+		}
+		if end < 0 {
+			end = length + end
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end {
+		return
+	}
+	offsets := make([]int, len(sets))
+	rank := 0
+	if policy == MergeAll {
+		rank = mergeSkip(sets, less, offsets, start)
+	}
+
+	h := &mergeHeap[K, T]{less: less}
+	for i, zs := range sets {
+		if offsets[i] < zs.ord.len() {
+			h.cursors = append(h.cursors, mergeCursor[K, T]{n: zs.ord.getNodeByRank(offsets[i] + 1), set: i})
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 && rank <= end {
+		c := h.cursors[0]
+		if policy == MergeAll || mergeKeep(sets, less, policy, c) {
+			if rank >= start && !iterator(c.n.item, rank+1) {
+				return
+			}
+			rank++
+		}
+		if next := c.n.level[0].forward; next != nil {
+			h.cursors[0].n = next
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+}
+
+// mergeLen returns the number of elements of the merged order of sets under
+// policy.
+func mergeLen[K comparable, T any](sets []*ZSet[K, T], policy MergePolicy) int {
+	length := 0
+	for i, zs := range sets {
+		if policy == MergeAll {
+			length += zs.ord.len()
+			continue
+		}
+		// count the keys not in the sets before.
+		for n := zs.ord.getMinNode(); n != nil; n = n.level[0].forward {
+			found := false
+			for _, other := range sets[:i] {
+				if other.lookup(n.key) != nil {
+					found = true
+					break
+				}
+			}
+			if !found {
+				length++
+			}
+		}
+	}
+	return length
+}
+
+// mergeSkip advances offsets over elements of the sets that come before index
+// start in the merged order, and returns their count. In every round, the set
+// whose element step elements ahead comes first skips them: each of the
+// active sets has at most step elements up to it, so the skipped elements
+// all come before start. They may not be a prefix of the merged order, but
+// the elements not skipped before start are then visited first, so the ranks
+// from start on are exact.
+func mergeSkip[K comparable, T any](sets []*ZSet[K, T], less LessFunc[T], offsets []int, start int) int {
+	skipped := 0
+	for {
+		active := 0
+		for i, zs := range sets {
+			if offsets[i] < zs.ord.len() {
+				active++
+			}
+		}
+		if active == 0 {
+			return skipped
+		}
+		step := (start - skipped) / active
+		if step == 0 {
+			return skipped
+		}
+		best, bestStep := -1, 0
+		var bestItem T
+		for i, zs := range sets {
+			s := zs.ord.len() - offsets[i]
+			if s > step {
+				s = step
+			}
+			if s == 0 {
+				continue
+			}
+			item := zs.ord.getNodeByRank(offsets[i] + s).item
+			if best < 0 || mergeBefore(less, item, i, bestItem, best) {
+				best, bestStep, bestItem = i, s, item
+			}
+		}
+		offsets[best] += bestStep
+		skipped += bestStep
+	}
+}
+
+// mergeKeep reports whether the element of cursor c is visited under policy,
+// that is, whether no other set has an element of its key that comes before it
+// for MergeFirst, or after it for MergeLast.
+func mergeKeep[K comparable, T any](sets []*ZSet[K, T], less LessFunc[T], policy MergePolicy, c mergeCursor[K, T]) bool {
+	for i, zs := range sets {
+		if i == c.set {
+			continue
+		}
+		n := zs.lookup(c.n.key)
+		if n == nil {
+			continue
+		}
+		if policy == MergeFirst && mergeBefore(less, n.item, i, c.n.item, c.set) ||
+			policy == MergeLast && mergeBefore(less, c.n.item, c.set, n.item, i) {
+			return false
+		}
+	}
+	return true
+}
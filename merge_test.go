@@ -0,0 +1,143 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestMergeRange(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	sets := []*ZSet[string, TestRank]{
+		New[string](less),
+		NewWithOptions(less, Options[string, TestRank]{Backend: BackendBTree, BTreeDegree: 4}),
+		New[string](less),
+		New[string](less), // empty
+	}
+	type elem struct {
+		item TestRank
+		set  int
+	}
+	var all []elem
+	for i, zs := range sets[:3] {
+		for j := 0; j < 100*(i+1); j++ {
+			// shared keys and few scores, so that there are duplicates and
+			// ties.
+			key := strconv.Itoa(rand.Intn(300))
+			zs.Add(key, TestRank{member: key, score: rand.Intn(50)})
+		}
+		zs.Range(0, -1, false, func(v TestRank, _ int) bool {
+			all = append(all, elem{v, i})
+			return true
+		})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return mergeBefore(less, all[i].item, all[i].set, all[j].item, all[j].set)
+	})
+	for _, policy := range []MergePolicy{MergeAll, MergeFirst, MergeLast} {
+		var want []elem
+		seen := map[string]int{}
+		for _, e := range all {
+			switch policy {
+			case MergeFirst:
+				if _, ok := seen[e.item.member]; ok {
+					continue
+				}
+				seen[e.item.member] = len(want)
+			case MergeLast:
+				if i, ok := seen[e.item.member]; ok {
+					want[i].set = -1
+				}
+				seen[e.item.member] = len(want)
+			}
+			want = append(want, e)
+		}
+		var kept []elem
+		for _, e := range want {
+			if e.set >= 0 {
+				kept = append(kept, e)
+			}
+		}
+		want = kept
+
+		for i := 0; i < 100; i++ {
+			start, end := rand.Intn(len(want)+10), rand.Intn(len(want)+10)
+			switch {
+			case i == 0:
+				start, end = 0, -1
+			case i%4 == 1:
+				// negative indexes count from the end, as in Range.
+				start = -rand.Intn(len(want) + 10)
+			case i%4 == 2:
+				end = -rand.Intn(len(want)+10) - 1
+			}
+			arg := [2]int{start, end}
+			if start < 0 {
+				start += len(want)
+			}
+			if end < 0 {
+				end += len(want)
+			}
+			if start < 0 {
+				start = 0
+			}
+			var got []TestRank
+			MergeRange(sets, less, policy, arg[0], arg[1], func(v TestRank, rank int) bool {
+				if rank != start+len(got)+1 {
+					t.Fatal(policy, "MergeRange rank error", arg, rank)
+				}
+				got = append(got, v)
+				return true
+			})
+			if end >= len(want) {
+				end = len(want) - 1
+			}
+			n := end - start + 1
+			if n < 0 {
+				n = 0
+			}
+			if len(got) != n {
+				t.Fatal(policy, "MergeRange length error", start, end, len(got), n)
+			}
+			for j, v := range got {
+				if want[start+j].item != v {
+					t.Fatal(policy, "MergeRange error", start, end, j, v, want[start+j].item)
+				}
+			}
+		}
+	}
+
+	var n int
+	MergeRange(sets, less, MergeAll, 10, -1, func(v TestRank, rank int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Error("MergeRange stop error", n)
+	}
+}
+
+func BenchmarkMergeRange(b *testing.B) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	sets := make([]*ZSet[string, TestRank], 8)
+	for i := range sets {
+		sets[i] = New[string](less)
+		for _, v := range perm(benchmarkListSize) {
+			sets[i].Add(v.member, v)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := i % (len(sets) * benchmarkListSize)
+		MergeRange(sets, less, MergeAll, start, start+9, func(v TestRank, rank int) bool {
+			return true
+		})
+	}
+}
@@ -0,0 +1,156 @@
+//go:build go1.18
+
+package zset
+
+// Index describes one ordering of the items of a MultiIndex.
+type Index[T any] struct {
+	Name string
+	Less LessFunc[T]
+}
+
+// MultiIndex is a set of items ordered in several ways at once. All the
+// orderings share one key dictionary, so that Add and Remove keep them in
+// sync. The item of a key is held once, by the dictionary, and the nodes of
+// the indexes point to it.
+type MultiIndex[K comparable, T any] struct {
+	dict    map[K]*indexEntry[K, T]
+	indexes []*skipList[K, *T]
+	names   map[string]int
+}
+
+// indexEntry is the item of a key of a MultiIndex and its nodes, one per
+// index.
+type indexEntry[K comparable, T any] struct {
+	item  T
+	nodes []*node[K, *T]
+}
+
+// NewMultiIndex creates a new MultiIndex with the given orderings. It panics
+// if there is no index or two indexes have the same name.
+func NewMultiIndex[K comparable, T any](indexes ...Index[T]) *MultiIndex[K, T] {
+	if len(indexes) == 0 {
+		panic("zset: no index")
+	}
+	mi := &MultiIndex[K, T]{
+		dict:  make(map[K]*indexEntry[K, T]),
+		names: make(map[string]int, len(indexes)),
+	}
+	for i, index := range indexes {
+		if _, ok := mi.names[index.Name]; ok {
+			panic("zset: duplicate index " + index.Name)
+		}
+		mi.names[index.Name] = i
+		less := index.Less
+		mi.indexes = append(mi.indexes, newSkipList(func(a, b *T) bool {
+			return less(*a, *b)
+		}, Options[K, *T]{}))
+	}
+	return mi
+}
+
+// Add a new element or update an existing element in every index. If an item
+// already exist, the previous item is returned.
+func (mi *MultiIndex[K, T]) Add(key K, item T) (removeItem T) {
+	e := mi.dict[key]
+	if e == nil {
+		e = &indexEntry[K, T]{item: item, nodes: make([]*node[K, *T], len(mi.indexes))}
+		for i, sl := range mi.indexes {
+			e.nodes[i] = sl.insert(key, &e.item)
+		}
+		mi.dict[key] = e
+		return
+	}
+	removeItem = e.item
+	// the nodes which would not stay at the same position are removed while
+	// they still point to the previous item, and inserted again after.
+	for i, sl := range mi.indexes {
+		if !sl.fits(e.nodes[i], &item) {
+			sl.delete(e.nodes[i])
+			e.nodes[i] = nil
+		}
+	}
+	e.item = item
+	for i, sl := range mi.indexes {
+		if e.nodes[i] == nil {
+			e.nodes[i] = sl.insert(key, &e.item)
+		}
+	}
+	return
+}
+
+// Remove the element from every index and return it.
+func (mi *MultiIndex[K, T]) Remove(key K) (removeItem T) {
+	e := mi.dict[key]
+	if e == nil {
+		return
+	}
+	for i, sl := range mi.indexes {
+		sl.delete(e.nodes[i])
+	}
+	delete(mi.dict, key)
+	return e.item
+}
+
+// Get return Item in dict.
+func (mi *MultiIndex[K, T]) Get(key K) (item T, found bool) {
+	if e, ok := mi.dict[key]; ok {
+		return e.item, ok
+	}
+	return
+}
+
+// Length return the element count
+func (mi *MultiIndex[K, T]) Length() int {
+	return len(mi.dict)
+}
+
+// Index returns the ordering with the given name. It panics if there is no
+// such index.
+func (mi *MultiIndex[K, T]) Index(name string) *IndexView[K, T] {
+	i, ok := mi.names[name]
+	if !ok {
+		panic("zset: unknown index " + name)
+	}
+	return &IndexView[K, T]{mi: mi, pos: i}
+}
+
+// IndexView queries one ordering of a MultiIndex.
+type IndexView[K comparable, T any] struct {
+	mi  *MultiIndex[K, T]
+	pos int
+}
+
+// Rank return 1-based rank in this index or 0 if not exist
+func (v *IndexView[K, T]) Rank(key K, reverse bool) int {
+	if e := v.mi.dict[key]; e != nil {
+		return v.mi.indexes[v.pos].rank(e.nodes[v.pos], reverse)
+	}
+	return 0
+}
+
+// Range calls the iterator for every value with in index range [start, end]
+// of this index, like ZSet.Range.
+func (v *IndexView[K, T]) Range(start, end int, reverse bool, iterator ItemIterator[T]) {
+	rangeByRank[K, *T](v.mi.indexes[v.pos], start, end, reverse, func(i *T, rank int) bool {
+		return iterator(*i, rank)
+	})
+}
+
+// RangeByScore calls the iterator for every value within the range [min, max]
+// of this index, like ZSet.RangeByScore.
+func (v *IndexView[K, T]) RangeByScore(min, max func(i T) bool, reverse bool, iterator ItemIterator[T]) {
+	rangeByScore[K, *T](v.mi.indexes[v.pos], deref(min), deref(max), reverse, func(i *T, rank int) bool {
+		return iterator(*i, rank)
+	})
+}
+
+// deref adapts a bound of RangeByScore to the items of the indexes, keeping
+// nil bounds nil.
+func deref[T any](f func(i T) bool) func(i *T) bool {
+	if f == nil {
+		return nil
+	}
+	return func(i *T) bool {
+		return f(*i)
+	}
+}
@@ -0,0 +1,140 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type TestPlayer struct {
+	name   string
+	score  int
+	level  int
+	active int
+}
+
+func newTestMultiIndex() *MultiIndex[string, TestPlayer] {
+	return NewMultiIndex[string](Index[TestPlayer]{
+		Name: "score",
+		Less: func(a, b TestPlayer) bool {
+			if a.score == b.score {
+				return a.name < b.name
+			}
+			return a.score < b.score
+		},
+	}, Index[TestPlayer]{
+		Name: "level",
+		Less: func(a, b TestPlayer) bool {
+			if a.level == b.level {
+				return a.name < b.name
+			}
+			return a.level < b.level
+		},
+	})
+}
+
+func TestMultiIndex(t *testing.T) {
+	mi := newTestMultiIndex()
+	for i := 0; i < 100; i++ {
+		name := strconv.Itoa(i)
+		mi.Add(name, TestPlayer{name: name, score: i, level: 99 - i})
+	}
+	if mi.Length() != 100 {
+		t.Error("Length error", mi.Length())
+	}
+	score, level := mi.Index("score"), mi.Index("level")
+	for i := 0; i < 100; i++ {
+		name := strconv.Itoa(i)
+		if r := score.Rank(name, false); r != i+1 {
+			t.Error("score rank error", name, r)
+		}
+		if r := level.Rank(name, false); r != 100-i {
+			t.Error("level rank error", name, r)
+		}
+	}
+
+	// update moves the item in every index
+	if old := mi.Add("0", TestPlayer{name: "0", score: 1000, level: 1000}); old.score != 0 {
+		t.Error("Add error", old)
+	}
+	if r := score.Rank("0", true); r != 1 {
+		t.Error("score rank error", r)
+	}
+	if r := level.Rank("0", true); r != 1 {
+		t.Error("level rank error", r)
+	}
+	if item, _ := mi.Get("0"); item.score != 1000 {
+		t.Error("Get error", item)
+	}
+
+	var names []string
+	level.Range(0, 2, false, func(i TestPlayer, _ int) bool {
+		names = append(names, i.name)
+		return true
+	})
+	if !reflect.DeepEqual(names, []string{"99", "98", "97"}) {
+		t.Error("Range error", names)
+	}
+	names = names[:0]
+	score.RangeByScore(func(i TestPlayer) bool {
+		return i.score >= 10
+	}, func(i TestPlayer) bool {
+		return i.score <= 12
+	}, true, func(i TestPlayer, _ int) bool {
+		names = append(names, i.name)
+		return true
+	})
+	if !reflect.DeepEqual(names, []string{"12", "11", "10"}) {
+		t.Error("RangeByScore error", names)
+	}
+
+	if old := mi.Remove("50"); old.score != 50 {
+		t.Error("Remove error", old)
+	}
+	if score.Rank("50", false) != 0 || level.Rank("50", false) != 0 || mi.Length() != 99 {
+		t.Error("Remove error")
+	}
+	if r := score.Rank("51", false); r != 50 {
+		t.Error("score rank error", r)
+	}
+}
+
+func TestMultiIndexConsistency(t *testing.T) {
+	mi := newTestMultiIndex()
+	model := make(map[string]TestPlayer)
+	for i := 0; i < 10000; i++ {
+		name := strconv.Itoa(rand.Intn(500))
+		if rand.Intn(3) == 0 {
+			mi.Remove(name)
+			delete(model, name)
+		} else {
+			p := TestPlayer{name: name, score: rand.Intn(100), level: rand.Intn(100)}
+			mi.Add(name, p)
+			model[name] = p
+		}
+	}
+	for _, name := range []string{"score", "level"} {
+		index, count := mi.Index(name), 0
+		index.Range(0, -1, false, func(i TestPlayer, rank int) bool {
+			count++
+			if model[i.name] != i || index.Rank(i.name, false) != rank {
+				t.Error("index error", name, i, rank)
+			}
+			return true
+		})
+		if count != len(model) {
+			t.Error("index length error", name, count, len(model))
+		}
+	}
+	// the indexes share the item of each key.
+	for key, e := range mi.dict {
+		for i, n := range e.nodes {
+			if n.item != &e.item {
+				t.Error("index holds its own item", key, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//go:build go1.18
+
+package zset
+
+// Multiset is a sorted multiset: a skip list of items without keys and
+// without a dict. Items that are equal, i.e. neither is less than the other,
+// may be inserted several times.
+type Multiset[T any] struct {
+	sl *skipList[struct{}, T]
+}
+
+// NewMultiset creates a new Multiset.
+func NewMultiset[T any](less LessFunc[T]) *Multiset[T] {
+	return &Multiset[T]{
+		sl: newSkipList(less, Options[struct{}, T]{}),
+	}
+}
+
+// Insert adds item to the multiset, before the items equal to it.
+func (ms *Multiset[T]) Insert(item T) {
+	ms.sl.insert(struct{}{}, item)
+}
+
+// first returns the first node equal to item and its 1-based rank, or nil.
+func (ms *Multiset[T]) first(item T) (*node[struct{}, T], int) {
+	compares := 0
+	n, rank := ms.sl.findNext(func(i T) bool {
+		return !ms.sl.lessCount(i, item, &compares)
+	})
+	ms.sl.counters.compare(compares)
+	if n == nil || ms.sl.lessThan(item, n.item) {
+		return nil, 0
+	}
+	return n, rank
+}
+
+// DeleteOne removes one item equal to item, and reports whether there was
+// one.
+func (ms *Multiset[T]) DeleteOne(item T) bool {
+	n, _ := ms.first(item)
+	if n == nil {
+		return false
+	}
+	ms.sl.delete(n)
+	return true
+}
+
+// DeleteAll removes all the items equal to item, and returns their count.
+func (ms *Multiset[T]) DeleteAll(item T) int {
+	n, _ := ms.first(item)
+	count := 0
+	for n != nil && !ms.sl.lessThan(item, n.item) {
+		next := n.level[0].forward
+		ms.sl.delete(n)
+		n = next
+		count++
+	}
+	return count
+}
+
+// Count returns the number of items equal to item.
+func (ms *Multiset[T]) Count(item T) int {
+	n, rank := ms.first(item)
+	if n == nil {
+		return 0
+	}
+	compares := 0
+	_, last := ms.sl.findPrev(func(i T) bool {
+		return !ms.sl.lessCount(item, i, &compares)
+	})
+	ms.sl.counters.compare(compares)
+	return last - rank + 1
+}
+
+// Rank returns the 1-based rank of the first item equal to item, or 0 if not
+// exist. If reverse, the items are ranked from the greatest, so the first item
+// equal to item is the last one in order.
+func (ms *Multiset[T]) Rank(item T, reverse bool) int {
+	if !reverse {
+		_, rank := ms.first(item)
+		return rank
+	}
+	compares := 0
+	n, rank := ms.sl.findPrev(func(i T) bool {
+		return !ms.sl.lessCount(item, i, &compares)
+	})
+	ms.sl.counters.compare(compares)
+	if rank == 0 || ms.sl.lessThan(n.item, item) {
+		return 0
+	}
+	return ms.sl.length - rank + 1
+}
+
+// Range calls the iterator for every item with in index range [start, end],
+// until iterator return false. The <start> and <stop> arguments represent
+// zero-based indexes.
+func (ms *Multiset[T]) Range(start, end int, reverse bool, iterator ItemIterator[T]) {
+	rangeByRank[struct{}, T](ms.sl, start, end, reverse, iterator)
+}
+
+// RangeByScore calls the iterator for every item within the range [min, max],
+// until iterator return false. If min is nil, it represents negative infinity.
+// If max is nil, it represents positive infinity.
+func (ms *Multiset[T]) RangeByScore(min, max func(i T) bool, reverse bool, iterator ItemIterator[T]) {
+	rangeByScore[struct{}, T](ms.sl, min, max, reverse, iterator)
+}
+
+// Length return the item count, duplicates included.
+func (ms *Multiset[T]) Length() int {
+	return ms.sl.length
+}
+
+// Validate checks the invariants of the multiset. See ZSet.Validate.
+func (ms *Multiset[T]) Validate() error {
+	_, err := ms.sl.validate()
+	return err
+}
@@ -0,0 +1,110 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestMultiset(t *testing.T) {
+	ms := NewMultiset(func(a, b int) bool {
+		return a < b
+	})
+	var ref []int
+	count := func(v int) int {
+		n := 0
+		for _, x := range ref {
+			if x == v {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < 5000; i++ {
+		v := rand.Intn(100)
+		switch rand.Intn(6) {
+		case 0:
+			n := count(v)
+			if got := ms.DeleteOne(v); got != (n > 0) {
+				t.Fatal("DeleteOne error", v, got)
+			}
+			if n > 0 {
+				j := sort.SearchInts(ref, v)
+				ref = append(ref[:j], ref[j+1:]...)
+			}
+		case 1:
+			n := count(v)
+			if got := ms.DeleteAll(v); got != n {
+				t.Fatal("DeleteAll error", v, got, n)
+			}
+			j := sort.SearchInts(ref, v)
+			ref = append(ref[:j], ref[j+n:]...)
+		default:
+			ms.Insert(v)
+			j := sort.SearchInts(ref, v)
+			ref = append(ref[:j], append([]int{v}, ref[j:]...)...)
+		}
+		if err := ms.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ms.Length() != len(ref) {
+		t.Fatal("Length error", ms.Length(), len(ref))
+	}
+
+	for v := -1; v <= 100; v++ {
+		n := count(v)
+		if got := ms.Count(v); got != n {
+			t.Fatal("Count error", v, got, n)
+		}
+		rank, reverseRank := 0, 0
+		if n > 0 {
+			j := sort.SearchInts(ref, v)
+			rank = j + 1
+			reverseRank = len(ref) - (j + n) + 1
+		}
+		if got := ms.Rank(v, false); got != rank {
+			t.Fatal("Rank error", v, got, rank)
+		}
+		if got := ms.Rank(v, true); got != reverseRank {
+			t.Fatal("reverse Rank error", v, got, reverseRank)
+		}
+	}
+
+	var got []int
+	ms.Range(0, -1, false, func(v int, rank int) bool {
+		if ref[rank-1] != v {
+			t.Fatal("Range error", rank, v)
+		}
+		got = append(got, v)
+		return true
+	})
+	if len(got) != len(ref) {
+		t.Fatal("Range length error", len(got), len(ref))
+	}
+
+	var want []int
+	for _, v := range ref {
+		if v >= 20 && v <= 30 {
+			want = append(want, v)
+		}
+	}
+	got = got[:0]
+	ms.RangeByScore(func(i int) bool { return i >= 20 }, func(i int) bool { return i <= 30 }, true, func(v int, rank int) bool {
+		if ref[len(ref)-rank] != v {
+			t.Fatal("RangeByScore rank error", rank, v)
+		}
+		got = append(got, v)
+		return true
+	})
+	if len(got) != len(want) {
+		t.Fatal("RangeByScore error", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[len(want)-1-i] {
+			t.Fatal("RangeByScore error", i, got[i])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+//go:build go1.18
+
+package zset
+
+// EventType is the kind of change reported by an Event.
+type EventType int
+
+const (
+	// EventAdd reports that a new element was added.
+	EventAdd EventType = iota
+	// EventUpdate reports that an existing element got a new item.
+	EventUpdate
+	// EventRemove reports that an element was removed.
+	EventRemove
+)
+
+// Event describes a change of one element of a ZSet.
+type Event[K comparable, T any] struct {
+	Type EventType
+	Key  K
+	// Old is the item before the change, zero for EventAdd.
+	Old T
+	// New is the item after the change, zero for EventRemove.
+	New T
+	// OldRank and NewRank are the 1-based ranks before and after the
+	// change, or 0 if the element did not exist.
+	OldRank, NewRank int
+}
+
+type observer[K comparable, T any] struct {
+	// fn is called with the event and the length of the set after the
+	// change.
+	fn func(e Event[K, T], length int)
+}
+
+// heldEvent is an event held back until the end of a transaction commit, with
+// the length of the set after the change.
+type heldEvent[K comparable, T any] struct {
+	e      Event[K, T]
+	length int
+}
+
+// Subscribe registers fn to be called synchronously after every change of the
+// set, from Add, Remove and the other methods that modify the set. It returns
+// a function which cancels the subscription. Ranks are only computed while
+// the set has subscribers.
+func (zs *ZSet[K, T]) Subscribe(fn func(Event[K, T])) (cancel func()) {
+	return zs.subscribe(func(e Event[K, T], _ int) {
+		fn(e)
+	})
+}
+
+// subscribe is like Subscribe, but also passes fn the length of the set after
+// the change, which is not the current length when held events are delivered.
+func (zs *ZSet[K, T]) subscribe(fn func(e Event[K, T], length int)) (cancel func()) {
+	o := &observer[K, T]{fn: fn}
+	zs.observers = append(zs.observers, o)
+	return func() {
+		// copy on write, so that notify can keep iterating the old slice.
+		observers := make([]*observer[K, T], 0, len(zs.observers))
+		for _, v := range zs.observers {
+			if v != o {
+				observers = append(observers, v)
+			}
+		}
+		zs.observers = observers
+	}
+}
+
+// SubscribeChan is like Subscribe, but delivers the events to a channel with
+// the given buffer size. Changes to the set block while the buffer is full.
+// Cancelling the subscription closes the channel; it must not be done
+// concurrently with changes to the set.
+func (zs *ZSet[K, T]) SubscribeChan(size int) (<-chan Event[K, T], func()) {
+	ch := make(chan Event[K, T], size)
+	cancel := zs.Subscribe(func(e Event[K, T]) {
+		ch <- e
+	})
+	var closed bool
+	return ch, func() {
+		if !closed {
+			closed = true
+			cancel()
+			close(ch)
+		}
+	}
+}
+
+func (zs *ZSet[K, T]) notify(e Event[K, T]) {
+	length := zs.ord.len()
+	if zs.hold {
+		zs.held = append(zs.held, heldEvent[K, T]{e: e, length: length})
+		return
+	}
+	for _, o := range zs.observers {
+		o.fn(e, length)
+	}
+}
+
+// holdEvents holds back the events of the set until releaseEvents, so that the
+// observers see the set after a batch of changes rather than in between.
+func (zs *ZSet[K, T]) holdEvents() {
+	zs.hold = true
+}
+
+// releaseEvents stops holding back the events of the set, and delivers the
+// held ones in order.
+func (zs *ZSet[K, T]) releaseEvents() {
+	held := zs.held
+	zs.hold, zs.held = false, nil
+	for _, h := range held {
+		for _, o := range zs.observers {
+			o.fn(h.e, h.length)
+		}
+	}
+}
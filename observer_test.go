@@ -0,0 +1,73 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		if a.score == b.score {
+			return a.member < b.member
+		}
+		return a.score < b.score
+	})
+	for _, v := range rang(10) {
+		zs.Add(v.member, v)
+	}
+
+	var events []Event[string, TestRank]
+	cancel := zs.Subscribe(func(e Event[string, TestRank]) {
+		events = append(events, e)
+	})
+
+	a := TestRank{member: "a", score: 5}
+	zs.Add("a", a)
+	b := TestRank{member: "a", score: 100}
+	zs.Add("a", b)
+	c := TestRank{member: "a", score: 101}
+	zs.Add("a", c) // updated in place
+	zs.Remove("3")
+	zs.Remove("x")
+	expect := []Event[string, TestRank]{
+		{Type: EventAdd, Key: "a", New: a, NewRank: 7},
+		{Type: EventUpdate, Key: "a", Old: a, New: b, OldRank: 7, NewRank: 11},
+		{Type: EventUpdate, Key: "a", Old: b, New: c, OldRank: 11, NewRank: 11},
+		{Type: EventRemove, Key: "3", Old: TestRank{member: "3", score: 3}, OldRank: 4},
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Error("Subscribe error", events)
+	}
+
+	cancel()
+	zs.Add("b", TestRank{member: "b"})
+	if len(events) != len(expect) {
+		t.Error("cancel error", events)
+	}
+}
+
+func TestSubscribeChan(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	ch, cancel := zs.SubscribeChan(10)
+	for _, v := range rang(5) {
+		zs.Add(v.member, v)
+	}
+	cancel()
+	cancel()
+	zs.Add("6", TestRank{member: "6", score: 6})
+
+	var ranks []int
+	for e := range ch {
+		if e.Type != EventAdd {
+			t.Error("event type error", e)
+		}
+		ranks = append(ranks, e.NewRank)
+	}
+	if !reflect.DeepEqual(ranks, []int{1, 2, 3, 4, 5}) {
+		t.Error("SubscribeChan error", ranks)
+	}
+}
@@ -0,0 +1,119 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Options configures a ZSet created by NewWithOptions. The zero value of every
+// field selects the default used by New.
+type Options[K comparable, T any] struct {
+	// Seed seeds the random generator of node levels, so that the same
+	// sequence of operations builds the same layout. 0 seeds from the clock.
+	Seed int64
+	// P is the probability of a node having one more level, in (0, 1).
+	// Default DefaultP.
+	P float64
+	// MaxLevel is the maximum level count of a node, in [1, DefaultMaxLevel].
+	// Default DefaultMaxLevel.
+	MaxLevel int
+	// FreeList is used to recycle the nodes of the set. It may be shared by
+	// several sets that are not used concurrently. Default a new free list of
+	// DefaultFreeListSize nodes.
+	FreeList *FreeList[K, T]
+	// InitialCapacity is a hint for the number of elements of the set.
+	InitialCapacity int
+	// CompactThreshold, if positive, makes the set start in a compact
+	// encoding that is promoted to the default one when the set grows beyond
+	// CompactThreshold elements. See DefaultCompactThreshold. It is ignored by
+	// BackendBTree.
+	CompactThreshold int
+	// Backend selects the ordered structure of the set. Default
+	// BackendSkipList. Seed, P and MaxLevel apply to BackendSkipList only.
+	Backend Backend
+	// BTreeDegree is the maximum number of children of a BackendBTree node,
+	// at least 4. Default DefaultBTreeDegree.
+	BTreeDegree int
+	// Finger makes the searches of a BackendSkipList set start from the
+	// position of the last insert rather than from the head, so that inserts
+	// and lookups near it, such as appends to a time ordered set, cost
+	// O(log d) comparisons for a distance d. Searches far from it cost up to
+	// one comparison more per level.
+	Finger bool
+}
+
+// NewWithOptions creates a new ZSet configured by opts. It panics if an
+// option is out of range.
+func NewWithOptions[K comparable, T any](less LessFunc[T], opts Options[K, T]) *ZSet[K, T] {
+	opts = opts.withDefaults()
+	capacity := opts.InitialCapacity
+	if capacity < 0 {
+		capacity = 0
+	}
+	if opts.Backend == BackendBTree {
+		return newZSet[K, T](newBTree(less, opts), capacity)
+	}
+	if opts.CompactThreshold > 0 {
+		sl := newCompactSkipList(less, opts)
+		return &ZSet[K, T]{
+			ord:     sl,
+			sl:      sl,
+			compact: &opts,
+		}
+	}
+	return newZSet[K, T](newSkipList(less, opts), capacity)
+}
+
+// withDefaults returns opts with MaxLevel, P and BTreeDegree set to their
+// defaults if zero. It panics if an option is out of range.
+func (opts Options[K, T]) withDefaults() Options[K, T] {
+	if opts.MaxLevel == 0 {
+		opts.MaxLevel = DefaultMaxLevel
+	}
+	if opts.MaxLevel < 1 || opts.MaxLevel > DefaultMaxLevel {
+		panic("zset: MaxLevel must be in [1, 32]")
+	}
+	if opts.P == 0 {
+		opts.P = DefaultP
+	}
+	if !(opts.P > 0 && opts.P < 1) {
+		panic("zset: P must be in (0, 1)")
+	}
+	if opts.BTreeDegree == 0 {
+		opts.BTreeDegree = DefaultBTreeDegree
+	}
+	if opts.BTreeDegree < 4 {
+		panic("zset: BTreeDegree must be at least 4")
+	}
+	if opts.Backend != BackendSkipList && opts.Backend != BackendBTree {
+		panic("zset: unknown Backend")
+	}
+	return opts
+}
+
+// newSkipList creates a skip list configured by opts.
+func newSkipList[K comparable, T any](less LessFunc[T], opts Options[K, T]) *skipList[K, T] {
+	opts = opts.withDefaults()
+	freelist := opts.FreeList
+	if freelist == nil {
+		freelist = NewFreeList[K, T](DefaultFreeListSize)
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &skipList[K, T]{
+		level: 1,
+		header: &node[K, T]{
+			level: make([]skipListLevel[K, T], opts.MaxLevel),
+		},
+		maxLevel:  opts.MaxLevel,
+		p:         opts.P,
+		freelist:  freelist,
+		random:    rand.New(rand.NewSource(seed)),
+		less:      less,
+		useFinger: opts.Finger,
+	}
+}
@@ -0,0 +1,78 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	heights := func(zs *ZSet[string, TestRank]) []int {
+		var h []int
+		for x := zs.sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+			h = append(h, len(x.level))
+		}
+		return h
+	}
+	items := perm(1000)
+	build := func(opts Options[string, TestRank]) *ZSet[string, TestRank] {
+		zs := NewWithOptions(less, opts)
+		for _, v := range items {
+			zs.Add(v.member, v)
+		}
+		if err := zs.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		return zs
+	}
+
+	a, b := build(Options[string, TestRank]{Seed: 42}), build(Options[string, TestRank]{Seed: 42})
+	if !reflect.DeepEqual(heights(a), heights(b)) {
+		t.Error("same seed built different layouts")
+	}
+
+	zs := build(Options[string, TestRank]{Seed: 1, MaxLevel: 3})
+	if zs.Stats().Level > 3 {
+		t.Error("MaxLevel not respected", zs.Stats().Level)
+	}
+	low, high := build(Options[string, TestRank]{Seed: 1, P: 0.05}), build(Options[string, TestRank]{Seed: 1, P: 0.75})
+	if low.Stats().LevelNodes[1] >= high.Stats().LevelNodes[1] {
+		t.Error("P not respected", low.Stats().LevelNodes, high.Stats().LevelNodes)
+	}
+
+	fl := NewFreeList[string, TestRank](100)
+	x := NewWithOptions(less, Options[string, TestRank]{FreeList: fl, InitialCapacity: 10})
+	y := NewWithOptions(less, Options[string, TestRank]{FreeList: fl})
+	for _, v := range perm(10) {
+		x.Add(v.member, v)
+	}
+	for _, v := range perm(10) {
+		x.Remove(v.member)
+	}
+	for _, v := range perm(10) {
+		y.Add(v.member, v)
+	}
+	if s := y.Stats(); s.FreeListHits != 10 || s.FreeListLen != 0 {
+		t.Error("shared FreeList error", s.FreeListHits, s.FreeListLen)
+	}
+
+	for _, opts := range []Options[string, TestRank]{
+		{MaxLevel: -1},
+		{MaxLevel: DefaultMaxLevel + 1},
+		{P: 1},
+		{P: -0.5},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("invalid options accepted", opts)
+				}
+			}()
+			NewWithOptions(less, opts)
+		}()
+	}
+}
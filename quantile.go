@@ -0,0 +1,106 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math"
+	"sort"
+)
+
+// QuantileMethod selects how a quantile is mapped onto the ranks of a set.
+type QuantileMethod int
+
+const (
+	// QuantileNearestRank picks the item with the smallest rank such that at
+	// least a fraction q of the items are ranked at or before it.
+	QuantileNearestRank QuantileMethod = iota
+	// QuantileLinear interpolates linearly between the two items closest to
+	// the fractional rank (n-1)*q+1.
+	QuantileLinear
+)
+
+// Percentile returns the percentage of items ranked before key, in [0, 100),
+// or -1 if not exist.
+func (zs *ZSet[K, T]) Percentile(key K) float64 {
+	rank := zs.Rank(key, false)
+	if rank == 0 {
+		return -1
+	}
+	return float64(rank-1) * 100 / float64(zs.ord.len())
+}
+
+// Quantile returns the nearest-rank q-quantile of the set for q in [0, 1], and
+// its 1-based rank. It returns rank 0 if the set is empty or q is out of range.
+func (zs *ZSet[K, T]) Quantile(q float64) (v T, rank int) {
+	rank, _ = quantileRank(q, zs.ord.len(), QuantileNearestRank)
+	if rank == 0 {
+		return
+	}
+	return zs.ord.getNodeByRank(rank).item, rank
+}
+
+// QuantileValue returns the q-quantile of the values of the items for q in
+// [0, 1], computed with method. It returns NaN if the set is empty or q is out
+// of range.
+func (zs *ZSet[K, T]) QuantileValue(q float64, method QuantileMethod, value func(T) float64) float64 {
+	return zs.Quantiles([]float64{q}, method, value)[0]
+}
+
+// Quantiles is like QuantileValue for many quantiles at once. The items are
+// visited in a single pass in rank order, and the results are returned in the
+// order of qs.
+func (zs *ZSet[K, T]) Quantiles(qs []float64, method QuantileMethod, value func(T) float64) []float64 {
+	type target struct {
+		index int
+		rank  int
+		frac  float64
+	}
+	targets := make([]target, 0, len(qs))
+	out := make([]float64, len(qs))
+	for i, q := range qs {
+		rank, frac := quantileRank(q, zs.ord.len(), method)
+		if rank == 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		targets = append(targets, target{index: i, rank: rank, frac: frac})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].rank < targets[j].rank
+	})
+
+	var x *node[K, T]
+	rank := 0
+	for _, t := range targets {
+		x = zs.ord.advance(x, rank, t.rank)
+		rank = t.rank
+		v := value(x.item)
+		if t.frac > 0 {
+			v += t.frac * (value(x.level[0].forward.item) - v)
+		}
+		out[t.index] = v
+	}
+	return out
+}
+
+// quantileRank maps q onto a 1-based rank of a set of n items, and the
+// fraction of the way towards the next rank. The rank is 0 if q is out of
+// range.
+func quantileRank(q float64, n int, method QuantileMethod) (rank int, frac float64) {
+	if n == 0 || !(q >= 0 && q <= 1) {
+		return 0, 0
+	}
+	switch method {
+	case QuantileLinear:
+		h := float64(n-1) * q
+		rank = int(h)
+		return rank + 1, h - float64(rank)
+	default:
+		// allow for rounding errors such as 0.7*10 = 7.000000000000001
+		rank = int(math.Ceil(q*float64(n) - 1e-9))
+		if rank < 1 {
+			rank = 1
+		}
+		return rank, 0
+	}
+}
@@ -0,0 +1,81 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	if p := zs.Percentile("0"); p != -1 {
+		t.Error("Percentile error", p)
+	}
+	for _, v := range perm(200) {
+		zs.Add(v.member, v)
+	}
+	for _, v := range rang(200) {
+		if p := zs.Percentile(v.member); p != float64(v.score)/2 {
+			t.Error("Percentile error", v.member, p)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	if _, rank := zs.Quantile(0.5); rank != 0 {
+		t.Error("Quantile on empty set", rank)
+	}
+	for _, v := range perm(10) {
+		zs.Add(v.member, v)
+	}
+
+	tests := []struct {
+		q      float64
+		expect int
+	}{
+		{0, 1}, {0.05, 1}, {0.1, 1}, {0.11, 2}, {0.5, 5}, {0.7, 7}, {0.99, 10}, {1, 10},
+	}
+	for _, tt := range tests {
+		v, rank := zs.Quantile(tt.q)
+		if rank != tt.expect || v.score != tt.expect-1 {
+			t.Error("Quantile error", tt.q, v, rank, tt.expect)
+		}
+	}
+	if _, rank := zs.Quantile(1.5); rank != 0 {
+		t.Error("Quantile out of range", rank)
+	}
+}
+
+func TestQuantiles(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	value := func(i TestRank) float64 {
+		return float64(i.score)
+	}
+	if v := zs.QuantileValue(0.5, QuantileLinear, value); !math.IsNaN(v) {
+		t.Error("QuantileValue on empty set", v)
+	}
+	for _, v := range perm(1000) {
+		zs.Add(v.member, v)
+	}
+
+	linear := zs.Quantiles([]float64{0.99, 0, 0.5, 1, 0.25, -1}, QuantileLinear, value)
+	if !reflect.DeepEqual(linear[:5], []float64{989.01, 0, 499.5, 999, 249.75}) || !math.IsNaN(linear[5]) {
+		t.Error("Quantiles linear error", linear)
+	}
+	nearest := zs.Quantiles([]float64{0.99, 0, 0.5, 1, 0.25}, QuantileNearestRank, value)
+	if !reflect.DeepEqual(nearest, []float64{989, 0, 499, 999, 249}) {
+		t.Error("Quantiles nearest rank error", nearest)
+	}
+	if v := zs.QuantileValue(0.5, QuantileLinear, value); v != 499.5 {
+		t.Error("QuantileValue error", v)
+	}
+}
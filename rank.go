@@ -0,0 +1,109 @@
+//go:build go1.18
+
+package zset
+
+import "math/bits"
+
+// RankMode selects how RankWithTies ranks items that are tied.
+type RankMode int
+
+const (
+	// RankCompetition gives tied items the rank of the first of them and
+	// leaves a gap after the tie ("1224" ranking).
+	RankCompetition RankMode = iota
+	// RankDense gives tied items the same rank without leaving a gap
+	// ("1223" ranking). As the groups of tied items depend on eq, they cannot
+	// be counted in advance: it walks the items ranked before the key, and
+	// searches for the end of the groups longer than log n instead of walking
+	// them. So a group of s items costs O(min(s, log n)).
+	RankDense
+	// RankFractional gives tied items the mean of the ranks they span
+	// ("1 2.5 2.5 4" ranking).
+	RankFractional
+)
+
+// RankWithTies return the 1-based rank of key, treating items for which eq
+// returns true as tied, or 0 if not exist. Items that are tied must be
+// adjacent under the set's LessFunc, e.g. eq compares only the score that
+// LessFunc orders by first.
+//
+// RankCompetition and RankFractional cost two searches. RankDense additionally
+// walks the groups of tied items ranked before key.
+func (zs *ZSet[K, T]) RankWithTies(key K, eq func(a, b T) bool, mode RankMode, reverse bool) float64 {
+	n := zs.lookup(key)
+	if n == nil {
+		return 0
+	}
+	first, firstRank, last, lastRank := findTies(zs.ord, n.item, eq)
+	switch mode {
+	case RankDense:
+		if reverse {
+			return float64(denseRank(zs.ord, zs.ord.getMaxNode(), last, eq, true))
+		}
+		return float64(denseRank(zs.ord, zs.ord.getMinNode(), first, eq, false))
+	case RankFractional:
+		rank := float64(firstRank+lastRank) / 2
+		if reverse {
+			return float64(zs.ord.len()+1) - rank
+		}
+		return rank
+	default:
+		if reverse {
+			return float64(zs.ord.len() - lastRank + 1)
+		}
+		return float64(firstRank)
+	}
+}
+
+// denseRank returns 1 plus the number of groups of tied items from x up to,
+// but not including, node end, going back if reverse. It walks the groups, and
+// jumps to the end of a group with a search once it has walked log n of its
+// items, so that a long group costs no more than a search.
+func denseRank[K comparable, T any](b backend[K, T], x, end *node[K, T], eq func(a, b T) bool, reverse bool) int {
+	walk := bits.Len(uint(b.len()))
+	rank := 1
+	for x != end {
+		// x is the first item of a group.
+		rank++
+		item, walked := x.item, 0
+		for x != end && eq(x.item, item) {
+			if walked == walk {
+				// a long group: jump to its last item.
+				if reverse {
+					x, _ = b.findNext(tieNext(b, item, eq))
+				} else {
+					x, _ = b.findPrev(tiePrev(b, item, eq))
+				}
+			}
+			if reverse {
+				x = x.backward
+			} else {
+				x = x.level[0].forward
+			}
+			walked++
+		}
+	}
+	return rank
+}
+
+// tieNext returns the findNext predicate for the first item tied with item.
+func tieNext[K comparable, T any](b backend[K, T], item T, eq func(a, b T) bool) func(i T) bool {
+	return func(i T) bool {
+		return !b.lessThan(i, item) || eq(i, item)
+	}
+}
+
+// tiePrev returns the findPrev predicate for the last item tied with item.
+func tiePrev[K comparable, T any](b backend[K, T], item T, eq func(a, b T) bool) func(i T) bool {
+	return func(i T) bool {
+		return !b.lessThan(item, i) || eq(i, item)
+	}
+}
+
+// findTies returns the first and last nodes tied with item, and their 1-based
+// ranks.
+func findTies[K comparable, T any](b backend[K, T], item T, eq func(a, b T) bool) (first *node[K, T], firstRank int, last *node[K, T], lastRank int) {
+	first, firstRank = b.findNext(tieNext(b, item, eq))
+	last, lastRank = b.findPrev(tiePrev(b, item, eq))
+	return
+}
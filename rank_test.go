@@ -0,0 +1,126 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestRankWithTies(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		if a.score == b.score {
+			return a.member < b.member
+		}
+		return a.score < b.score
+	})
+	// scores: a=1 b=2 c=2 d=3 e=3 f=3 g=4
+	for i, score := range []int{1, 2, 2, 3, 3, 3, 4} {
+		member := string(rune('a' + i))
+		zs.Add(member, TestRank{member: member, score: score})
+	}
+	eq := func(a, b TestRank) bool {
+		return a.score == b.score
+	}
+
+	tests := []struct {
+		key     string
+		mode    RankMode
+		reverse bool
+		expect  float64
+	}{
+		{"a", RankCompetition, false, 1},
+		{"c", RankCompetition, false, 2},
+		{"f", RankCompetition, false, 4},
+		{"g", RankCompetition, false, 7},
+		{"b", RankCompetition, true, 5},
+		{"e", RankCompetition, true, 2},
+		{"a", RankDense, false, 1},
+		{"c", RankDense, false, 2},
+		{"e", RankDense, false, 3},
+		{"g", RankDense, false, 4},
+		{"g", RankDense, true, 1},
+		{"d", RankDense, true, 2},
+		{"a", RankDense, true, 4},
+		{"b", RankFractional, false, 2.5},
+		{"e", RankFractional, false, 5},
+		{"g", RankFractional, false, 7},
+		{"b", RankFractional, true, 5.5},
+		{"x", RankCompetition, false, 0},
+	}
+	for _, tt := range tests {
+		if r := zs.RankWithTies(tt.key, eq, tt.mode, tt.reverse); r != tt.expect {
+			t.Error("RankWithTies error", tt.key, tt.mode, tt.reverse, r, tt.expect)
+		}
+	}
+}
+
+func TestRankWithTiesNoTies(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(100) {
+		zs.Add(v.member, v)
+	}
+	eq := func(a, b TestRank) bool {
+		return a.score == b.score
+	}
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		for _, mode := range []RankMode{RankCompetition, RankDense, RankFractional} {
+			if r := zs.RankWithTies(key, eq, mode, false); r != float64(zs.Rank(key, false)) {
+				t.Error("RankWithTies error", key, mode, r)
+			}
+			if r := zs.RankWithTies(key, eq, mode, true); r != float64(zs.Rank(key, true)) {
+				t.Error("RankWithTies reverse error", key, mode, r)
+			}
+		}
+	}
+}
+
+func TestRankDense(t *testing.T) {
+	for _, backend := range []Backend{BackendSkipList, BackendBTree} {
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{Backend: backend})
+		// groups of 1 to 300 tied items, shorter and longer than log n.
+		var scores []int
+		for score := 0; len(scores) < 5000; score++ {
+			for j := rand.Intn(300) + 1; j > 0; j-- {
+				scores = append(scores, score)
+			}
+			for j := rand.Intn(20); j > 0; j-- {
+				score++
+				scores = append(scores, score)
+			}
+		}
+		for i, score := range scores {
+			zs.Add(strconv.Itoa(i), TestRank{member: strconv.Itoa(i), score: score})
+		}
+		eq := func(a, b TestRank) bool {
+			return a.score == b.score
+		}
+		// the dense rank of a score is its index among the distinct scores.
+		var distinct []int
+		zs.Range(0, -1, false, func(v TestRank, _ int) bool {
+			if len(distinct) == 0 || distinct[len(distinct)-1] != v.score {
+				distinct = append(distinct, v.score)
+			}
+			return true
+		})
+		dense := make(map[int]int)
+		for i, score := range distinct {
+			dense[score] = i + 1
+		}
+		for i := 0; i < len(scores); i += 7 {
+			key := strconv.Itoa(i)
+			if r := zs.RankWithTies(key, eq, RankDense, false); r != float64(dense[scores[i]]) {
+				t.Fatal(backend, "RankDense error", key, r, dense[scores[i]])
+			}
+			if r := zs.RankWithTies(key, eq, RankDense, true); r != float64(len(distinct)-dense[scores[i]]+1) {
+				t.Fatal(backend, "RankDense reverse error", key, r, len(distinct)-dense[scores[i]]+1)
+			}
+		}
+	}
+}
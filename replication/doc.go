@@ -0,0 +1,16 @@
+// Package replication keeps read-only copies of a zset.ZSet in other
+// processes, such as hot standbys.
+//
+// A Primary owns a set, records every Add and Remove made through its Update
+// method in a numbered feed, and serves it to replicas over any
+// io.ReadWriter, for example a TCP connection. A Replica receives a snapshot
+// of the set followed by the feed, and applies it to its own set. The offset
+// of a replica is the number of the last change it applied; after a
+// disconnect, it resumes from its offset if the primary still holds the
+// following changes in its backlog, and receives a new snapshot otherwise.
+//
+// Keys and items are encoded with encoding/gob, so their fields must be
+// exported.
+//
+// It requires go1.18 or later.
+package replication
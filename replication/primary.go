@@ -0,0 +1,175 @@
+//go:build go1.18
+
+package replication
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	"github.com/liwnn/zset"
+)
+
+// DefaultBacklogSize is the default number of changes a Primary keeps for
+// replicas to resume from.
+const DefaultBacklogSize = 1 << 16
+
+// Primary serves the changes of a set to replicas.
+type Primary[K comparable, T any] struct {
+	mu      sync.Mutex
+	changed *sync.Cond // broadcast on new changes, on Close and on disconnects
+	set     *zset.ZSet[K, T]
+	id      string
+	offset  uint64
+	// backlog is a ring of the last changes: the change of offset o is at
+	// index (o-1) % len(backlog).
+	backlog []Change[K, T]
+	closed  bool
+}
+
+// NewPrimary creates a Primary of set, which keeps the last backlogSize
+// changes for replicas to resume from. A backlogSize of 0 means
+// DefaultBacklogSize. From then on, set must only be used through Update and
+// View.
+func NewPrimary[K comparable, T any](set *zset.ZSet[K, T], backlogSize int) *Primary[K, T] {
+	if backlogSize <= 0 {
+		backlogSize = DefaultBacklogSize
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	p := &Primary[K, T]{
+		set:     set,
+		id:      hex.EncodeToString(id[:]),
+		backlog: make([]Change[K, T], backlogSize),
+	}
+	p.changed = sync.NewCond(&p.mu)
+	set.Subscribe(p.record)
+	return p
+}
+
+// record appends the change of e to the feed. It runs within Update.
+func (p *Primary[K, T]) record(e zset.Event[K, T]) {
+	c := Change[K, T]{Op: OpAdd, Key: e.Key, Item: e.New}
+	if e.Type == zset.EventRemove {
+		c.Op = OpRemove
+	}
+	p.offset++
+	c.Offset = p.offset
+	p.backlog[(p.offset-1)%uint64(len(p.backlog))] = c
+}
+
+// Update calls fn to change the set. Every Add and Remove made by fn is sent
+// to the replicas.
+func (p *Primary[K, T]) Update(fn func(set *zset.ZSet[K, T])) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	offset := p.offset
+	fn(p.set)
+	if p.offset != offset {
+		p.changed.Broadcast()
+	}
+}
+
+// View calls fn to read the set. fn must not change it.
+func (p *Primary[K, T]) View(fn func(set *zset.ZSet[K, T])) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(p.set)
+}
+
+// Offset returns the offset of the last change of the set.
+func (p *Primary[K, T]) Offset() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.offset
+}
+
+// Close stops all the calls to Serve.
+func (p *Primary[K, T]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.changed.Broadcast()
+}
+
+// Serve serves one replica over rw, until an error occurs, the replica
+// disconnects or the primary is closed. It returns ErrClosed if the primary
+// is closed, ErrLagged if the replica falls behind the backlog, and the error
+// of rw otherwise, such as io.EOF when the replica disconnects. It is usually
+// run in a goroutine per connection, which closes rw when Serve returns.
+func (p *Primary[K, T]) Serve(rw io.ReadWriter) error {
+	var h hello
+	if err := gob.NewDecoder(rw).Decode(&h); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(rw)
+
+	// a replica sends nothing after its hello, so reading rw only returns
+	// when it disconnects, even while there are no changes to send. The
+	// reader ends when rw is closed.
+	var readErr error // guarded by p.mu
+	go func() {
+		_, err := io.Copy(io.Discard, rw)
+		if err == nil {
+			err = io.EOF
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		readErr = err
+		p.changed.Broadcast()
+	}()
+
+	p.mu.Lock()
+	f := frame[K, T]{ID: p.id, Offset: h.Offset}
+	if h.ID != p.id || !p.inBacklog(h.Offset) {
+		f.Snapshot, f.Offset = true, p.offset
+		f.Elements = make([]Element[K, T], 0, p.set.Length())
+		p.set.RangeWithKeys(0, -1, false, func(key K, item T, _ int) bool {
+			f.Elements = append(f.Elements, Element[K, T]{Key: key, Item: item})
+			return true
+		})
+	}
+	p.mu.Unlock()
+	if err := enc.Encode(&f); err != nil {
+		return err
+	}
+
+	next := f.Offset + 1
+	for {
+		p.mu.Lock()
+		for !p.closed && readErr == nil && p.offset < next {
+			p.changed.Wait()
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return ErrClosed
+		}
+		if readErr != nil {
+			err := readErr
+			p.mu.Unlock()
+			return err
+		}
+		if !p.inBacklog(next - 1) {
+			p.mu.Unlock()
+			return ErrLagged
+		}
+		f := frame[K, T]{Changes: make([]Change[K, T], 0, p.offset-next+1)}
+		for o := next; o <= p.offset; o++ {
+			f.Changes = append(f.Changes, p.backlog[(o-1)%uint64(len(p.backlog))])
+		}
+		next = p.offset + 1
+		p.mu.Unlock()
+		if err := enc.Encode(&f); err != nil {
+			return err
+		}
+	}
+}
+
+// inBacklog reports whether the changes after offset are all in the backlog.
+func (p *Primary[K, T]) inBacklog(offset uint64) bool {
+	return offset <= p.offset && p.offset-offset <= uint64(len(p.backlog))
+}
@@ -0,0 +1,61 @@
+//go:build go1.18
+
+package replication
+
+import "errors"
+
+// Op is the kind of a change of the feed.
+type Op int
+
+const (
+	// OpAdd adds or updates the element of a key.
+	OpAdd Op = iota
+	// OpRemove removes the element of a key.
+	OpRemove
+)
+
+// Change is one numbered change of the feed of a Primary.
+type Change[K comparable, T any] struct {
+	Offset uint64
+	Op     Op
+	Key    K
+	Item   T
+}
+
+// Element is a key and its item in a snapshot.
+type Element[K comparable, T any] struct {
+	Key  K
+	Item T
+}
+
+// hello is sent by a replica when it connects.
+type hello struct {
+	// ID and Offset are the primary and offset the replica resumes from, or
+	// empty for a replica that has never synced.
+	ID     string
+	Offset uint64
+}
+
+// frame is sent by a primary: first a snapshot or a resume frame, then
+// frames of changes.
+type frame[K comparable, T any] struct {
+	// ID identifies the primary in the first frame.
+	ID string
+	// Snapshot, in the first frame, reports that Elements is the whole set
+	// as of Offset. Otherwise the replica resumes from its offset.
+	Snapshot bool
+	Offset   uint64
+	Elements []Element[K, T]
+	Changes  []Change[K, T]
+}
+
+var (
+	// ErrClosed is returned by Primary.Serve when the primary is closed.
+	ErrClosed = errors.New("replication: primary closed")
+	// ErrLagged is returned by Primary.Serve when the replica falls behind
+	// the backlog. It receives a new snapshot when it connects again.
+	ErrLagged = errors.New("replication: replica fell behind the backlog")
+	// ErrOutOfSync is returned by Replica.Sync when the feed skips a
+	// change. The replica receives a new snapshot when it syncs again.
+	ErrOutOfSync = errors.New("replication: feed out of sync")
+)
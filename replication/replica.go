@@ -0,0 +1,109 @@
+//go:build go1.18
+
+package replication
+
+import (
+	"encoding/gob"
+	"io"
+	"sync"
+
+	"github.com/liwnn/zset"
+)
+
+// Replica applies the feed of a Primary to its own set.
+type Replica[K comparable, T any] struct {
+	mu        sync.RWMutex
+	less      zset.LessFunc[T]
+	set       *zset.ZSet[K, T]
+	id        string
+	offset    uint64
+	snapshots int
+}
+
+// NewReplica creates an empty Replica whose set is ordered by less, which
+// must order items like the set of the primary.
+func NewReplica[K comparable, T any](less zset.LessFunc[T]) *Replica[K, T] {
+	return &Replica[K, T]{
+		less: less,
+		set:  zset.New[K](less),
+	}
+}
+
+// Sync connects the replica to a primary over rw and applies its feed, until
+// an error occurs. It returns the error of rw, such as io.EOF when the
+// primary disconnects, or ErrOutOfSync. Calling Sync again, usually over a
+// new connection, resumes from the offset of the replica.
+func (r *Replica[K, T]) Sync(rw io.ReadWriter) error {
+	r.mu.Lock()
+	h := hello{ID: r.id, Offset: r.offset}
+	r.mu.Unlock()
+	if err := gob.NewEncoder(rw).Encode(&h); err != nil {
+		return err
+	}
+	dec := gob.NewDecoder(rw)
+	for first := true; ; first = false {
+		// gob leaves the fields missing from the stream as they are, so
+		// decode every frame into a new one.
+		var f frame[K, T]
+		if err := dec.Decode(&f); err != nil {
+			return err
+		}
+		if err := r.apply(&f, first); err != nil {
+			return err
+		}
+	}
+}
+
+// apply applies a frame received by Sync.
+func (r *Replica[K, T]) apply(f *frame[K, T], first bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if first {
+		r.id = f.ID
+		if f.Snapshot {
+			set := zset.NewWithOptions(r.less, zset.Options[K, T]{InitialCapacity: len(f.Elements)})
+			for _, e := range f.Elements {
+				set.Add(e.Key, e.Item)
+			}
+			r.set, r.offset = set, f.Offset
+			r.snapshots++
+		}
+	}
+	for _, c := range f.Changes {
+		if c.Offset != r.offset+1 {
+			// resync from a snapshot next time.
+			r.id = ""
+			return ErrOutOfSync
+		}
+		switch c.Op {
+		case OpAdd:
+			r.set.Add(c.Key, c.Item)
+		case OpRemove:
+			r.set.Remove(c.Key)
+		}
+		r.offset = c.Offset
+	}
+	return nil
+}
+
+// View calls fn to read the set of the replica. fn must not change it.
+func (r *Replica[K, T]) View(fn func(set *zset.ZSet[K, T])) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn(r.set)
+}
+
+// Offset returns the offset of the last change applied by the replica.
+func (r *Replica[K, T]) Offset() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.offset
+}
+
+// Snapshots returns the number of snapshots the replica has received, that
+// is, the number of full resyncs.
+func (r *Replica[K, T]) Snapshots() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.snapshots
+}
@@ -0,0 +1,191 @@
+//go:build go1.18
+
+package replication_test
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/liwnn/zset"
+	"github.com/liwnn/zset/replication"
+	"github.com/liwnn/zset/zsettest"
+)
+
+type (
+	primary = replication.Primary[string, zsettest.Item]
+	replica = replication.Replica[string, zsettest.Item]
+)
+
+// connect runs a replica against a primary over a pipe, and returns a
+// function which disconnects them and returns the error of Sync.
+func connect(p *primary, r *replica) (disconnect func() error) {
+	c1, c2 := net.Pipe()
+	go p.Serve(c1)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Sync(c2)
+	}()
+	return func() error {
+		c1.Close()
+		c2.Close()
+		return <-done
+	}
+}
+
+// update makes n random changes to the set of p.
+func update(p *primary, n int) {
+	p.Update(func(zs *zset.ZSet[string, zsettest.Item]) {
+		for i := 0; i < n; i++ {
+			key := strconv.Itoa(rand.Intn(100))
+			if rand.Intn(4) == 0 {
+				zs.Remove(key)
+			} else {
+				zs.Add(key, zsettest.Item{Key: key, Score: rand.Intn(1000)})
+			}
+		}
+	})
+}
+
+// elements returns the keys and items of a set in order.
+func elements(zs *zset.ZSet[string, zsettest.Item]) (out []replication.Element[string, zsettest.Item]) {
+	zs.RangeWithKeys(0, -1, false, func(key string, item zsettest.Item, _ int) bool {
+		out = append(out, replication.Element[string, zsettest.Item]{Key: key, Item: item})
+		return true
+	})
+	return
+}
+
+// checkSynced waits for r to have received snapshots snapshots and to reach
+// the offset of p, and compares their sets. The offsets of different primaries
+// may be equal, so the snapshot count tells when a resync is applied.
+func checkSynced(t *testing.T, p *primary, r *replica, snapshots int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Snapshots() != snapshots || r.Offset() != p.Offset() {
+		if time.Now().After(deadline) {
+			t.Fatal("replica not synced", r.Snapshots(), r.Offset(), p.Offset())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var want, got []replication.Element[string, zsettest.Item]
+	p.View(func(zs *zset.ZSet[string, zsettest.Item]) {
+		want = elements(zs)
+	})
+	r.View(func(zs *zset.ZSet[string, zsettest.Item]) {
+		got = elements(zs)
+		if err := zs.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("replica differs from primary", got, want)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	p := replication.NewPrimary(zset.New[string](zsettest.Less), 100)
+	defer p.Close()
+	update(p, 50)
+
+	r := replication.NewReplica[string](zsettest.Less)
+	disconnect := connect(p, r)
+	checkSynced(t, p, r, 1)
+	for i := 0; i < 20; i++ {
+		update(p, 5)
+	}
+	checkSynced(t, p, r, 1)
+	if err := disconnect(); err == nil {
+		t.Error("Sync returned no error")
+	}
+
+	// resume from the backlog.
+	update(p, 80)
+	disconnect = connect(p, r)
+	checkSynced(t, p, r, 1)
+	disconnect()
+	if n := r.Snapshots(); n != 1 {
+		t.Error("resume took a snapshot", n)
+	}
+
+	// fall behind the backlog.
+	update(p, 300)
+	disconnect = connect(p, r)
+	checkSynced(t, p, r, 2)
+	disconnect()
+
+	// a new primary is resynced from a snapshot.
+	p2 := replication.NewPrimary(zset.New[string](zsettest.Less), 100)
+	defer p2.Close()
+	update(p2, 10)
+	for p2.Offset() < r.Offset() {
+		update(p2, 10)
+	}
+	disconnect = connect(p2, r)
+	checkSynced(t, p2, r, 3)
+	disconnect()
+}
+
+func TestServeDisconnect(t *testing.T) {
+	p := replication.NewPrimary(zset.New[string](zsettest.Less), 0)
+	update(p, 50)
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	served := make(chan error, 1)
+	go func() {
+		served <- p.Serve(c1)
+	}()
+	r := replication.NewReplica[string](zsettest.Less)
+	go r.Sync(c2)
+	checkSynced(t, p, r, 1)
+
+	// Serve returns while the primary is idle.
+	c2.Close()
+	select {
+	case err := <-served:
+		if err == nil || errors.Is(err, replication.ErrClosed) {
+			t.Error("Serve error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the replica disconnected")
+	}
+}
+
+func TestReplicationTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	p := replication.NewPrimary(zset.New[string](zsettest.Less), 0)
+	update(p, 50)
+	served := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			served <- err
+			return
+		}
+		defer conn.Close()
+		served <- p.Serve(conn)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := replication.NewReplica[string](zsettest.Less)
+	go r.Sync(conn)
+	update(p, 50)
+	checkSynced(t, p, r, 1)
+
+	p.Close()
+	if err := <-served; !errors.Is(err, replication.ErrClosed) {
+		t.Error("Serve error", err)
+	}
+}
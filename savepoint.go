@@ -0,0 +1,115 @@
+//go:build go1.18
+
+package zset
+
+import "errors"
+
+// SavepointID identifies a savepoint of a set.
+type SavepointID int
+
+// ErrUnknownSavepoint is returned for a savepoint which does not exist or was
+// released.
+var ErrUnknownSavepoint = errors.New("zset: unknown savepoint")
+
+// undoLog records how to undo the changes of a set since its oldest
+// savepoint.
+type undoLog[K comparable, T any] struct {
+	ops        []undoOp[K, T]
+	savepoints []savepoint // oldest first
+	lastID     SavepointID
+}
+
+// undoOp restores the item of key, or removes key if it did not exist.
+type undoOp[K comparable, T any] struct {
+	key     K
+	item    T
+	existed bool
+}
+
+// savepoint is a position in the undo log.
+type savepoint struct {
+	id  SavepointID
+	pos int
+}
+
+// Savepoint marks the current state of the set, which RollbackTo restores.
+// While the set has savepoints, every Add and Remove records its inverse, so
+// rolling back costs one change per change undone. Savepoints nest: rolling
+// back to one, or releasing it, also drops the savepoints created after it.
+func (zs *ZSet[K, T]) Savepoint() SavepointID {
+	if zs.undo == nil {
+		zs.undo = &undoLog[K, T]{}
+	}
+	zs.undo.lastID++
+	zs.undo.savepoints = append(zs.undo.savepoints, savepoint{id: zs.undo.lastID, pos: len(zs.undo.ops)})
+	return zs.undo.lastID
+}
+
+// RollbackTo undoes the changes of the set made since savepoint id, which is
+// kept. The restored elements are added back, so an element may be ranked in
+// another order among the items equal to it under the LessFunc. Observers are
+// notified of the changes undone.
+func (zs *ZSet[K, T]) RollbackTo(id SavepointID) error {
+	i := zs.findSavepoint(id)
+	if i < 0 {
+		return ErrUnknownSavepoint
+	}
+	log := zs.undo
+	pos := log.savepoints[i].pos
+	// don't record the changes made to undo.
+	zs.undo = nil
+	for j := len(log.ops) - 1; j >= pos; j-- {
+		op := log.ops[j]
+		if op.existed {
+			zs.Add(op.key, op.item)
+		} else {
+			zs.Remove(op.key)
+		}
+	}
+	zs.undo = log
+	var zero undoOp[K, T]
+	for j := pos; j < len(log.ops); j++ {
+		log.ops[j] = zero
+	}
+	log.ops = log.ops[:pos]
+	log.savepoints = log.savepoints[:i+1]
+	return nil
+}
+
+// Release drops savepoint id and the savepoints created after it, keeping the
+// changes made since. When the set has no savepoint left, it stops recording
+// its changes.
+func (zs *ZSet[K, T]) Release(id SavepointID) error {
+	i := zs.findSavepoint(id)
+	if i < 0 {
+		return ErrUnknownSavepoint
+	}
+	if i == 0 {
+		zs.undo = nil
+		return nil
+	}
+	zs.undo.savepoints = zs.undo.savepoints[:i]
+	return nil
+}
+
+// findSavepoint returns the index of savepoint id, or -1 if not exist.
+func (zs *ZSet[K, T]) findSavepoint(id SavepointID) int {
+	if zs.undo == nil {
+		return -1
+	}
+	for i, sp := range zs.undo.savepoints {
+		if sp.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordUndo records how to undo a change of key, before it is made.
+func (zs *ZSet[K, T]) recordUndo(key K) {
+	op := undoOp[K, T]{key: key}
+	if n := zs.lookup(key); n != nil {
+		op.item, op.existed = n.item, true
+	}
+	zs.undo.ops = append(zs.undo.ops, op)
+}
@@ -0,0 +1,94 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSavepoint(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		if a.score == b.score {
+			return a.member < b.member
+		}
+		return a.score < b.score
+	})
+	items := func() (out []TestRank) {
+		zs.Range(0, -1, false, func(v TestRank, _ int) bool {
+			out = append(out, v)
+			return true
+		})
+		return
+	}
+	change := func(n int) {
+		for i := 0; i < n; i++ {
+			key := strconv.Itoa(rand.Intn(100))
+			if rand.Intn(3) == 0 {
+				zs.Remove(key)
+			} else {
+				zs.Add(key, TestRank{member: key, score: rand.Intn(100)})
+			}
+		}
+	}
+	change(100)
+	if zs.undo != nil {
+		t.Fatal("changes recorded without savepoint")
+	}
+
+	var ids []SavepointID
+	var states [][]TestRank
+	for i := 0; i < 5; i++ {
+		ids = append(ids, zs.Savepoint())
+		states = append(states, items())
+		change(50)
+	}
+	if len(zs.undo.ops) > 5*50 {
+		t.Error("undo log too long", len(zs.undo.ops))
+	}
+
+	var events int
+	cancel := zs.Subscribe(func(e Event[string, TestRank]) {
+		events++
+	})
+	if err := zs.RollbackTo(ids[3]); err != nil || !reflect.DeepEqual(items(), states[3]) {
+		t.Fatal("RollbackTo error", err)
+	}
+	cancel()
+	if events == 0 {
+		t.Error("RollbackTo did not notify")
+	}
+	if err := zs.RollbackTo(ids[4]); err != ErrUnknownSavepoint {
+		t.Error("rolled back to a dropped savepoint", err)
+	}
+
+	// the savepoint is kept after a rollback.
+	change(50)
+	if err := zs.RollbackTo(ids[3]); err != nil || !reflect.DeepEqual(items(), states[3]) {
+		t.Fatal("second RollbackTo error", err)
+	}
+
+	// releasing keeps the changes, and the outer savepoints.
+	change(50)
+	if err := zs.Release(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := zs.RollbackTo(ids[2]); err != ErrUnknownSavepoint {
+		t.Error("rolled back to a released savepoint", err)
+	}
+	if err := zs.RollbackTo(ids[1]); err != nil || !reflect.DeepEqual(items(), states[1]) {
+		t.Fatal("RollbackTo after Release error", err)
+	}
+
+	if err := zs.Release(ids[0]); err != nil || zs.undo != nil {
+		t.Fatal("Release error", err)
+	}
+	if err := zs.RollbackTo(ids[0]); err != ErrUnknownSavepoint {
+		t.Error("rolled back to a released savepoint", err)
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"errors"
+	"math"
+)
+
+// Ordered is a constraint that permits any ordered type: any type that
+// supports the operators < <= >= >. It mirrors cmp.Ordered, which is not
+// available before go1.21.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
+// ErrNaN is returned when a score is, or would become, NaN.
+var ErrNaN = errors.New("zset: score is not a number (NaN)")
+
+// ScoredItem is a member of a Scored set together with its score.
+type ScoredItem[K Ordered] struct {
+	Member K
+	Score  float64
+}
+
+// ScoreBound is one end of a score range. Use -Inf/+Inf values for unbounded
+// ranges.
+type ScoreBound struct {
+	Value     float64
+	Exclusive bool
+}
+
+var (
+	// NegInf is the lowest possible score bound, like "-inf" in redis.
+	NegInf = ScoreBound{Value: math.Inf(-1)}
+	// PosInf is the highest possible score bound, like "+inf" in redis.
+	PosInf = ScoreBound{Value: math.Inf(1)}
+)
+
+// Inclusive returns a bound that includes score, like "1.5" in redis.
+func Inclusive(score float64) ScoreBound {
+	return ScoreBound{Value: score}
+}
+
+// Exclusive returns a bound that excludes score, like "(1.5" in redis.
+func Exclusive(score float64) ScoreBound {
+	return ScoreBound{Value: score, Exclusive: true}
+}
+
+// greater returns the predicate for items at or above a min bound.
+func (b ScoreBound) greater() func(score float64) bool {
+	if b.Exclusive {
+		return func(score float64) bool { return score > b.Value }
+	}
+	return func(score float64) bool { return score >= b.Value }
+}
+
+// less returns the predicate for items at or below a max bound.
+func (b ScoreBound) less() func(score float64) bool {
+	if b.Exclusive {
+		return func(score float64) bool { return score < b.Value }
+	}
+	return func(score float64) bool { return score <= b.Value }
+}
+
+// Scored is a redis style sorted set of members with float64 scores.
+// Members with the same score are ordered by member.
+type Scored[K Ordered] struct {
+	zs *ZSet[K, ScoredItem[K]]
+}
+
+// NewScored creates a new Scored set.
+func NewScored[K Ordered]() *Scored[K] {
+	return &Scored[K]{
+		zs: New[K](func(a, b ScoredItem[K]) bool {
+			if a.Score == b.Score {
+				return a.Member < b.Member
+			}
+			return a.Score < b.Score
+		}),
+	}
+}
+
+// ZAdd adds member with score, or updates the score of an existing member.
+// It returns ErrNaN if score is NaN.
+func (s *Scored[K]) ZAdd(member K, score float64) error {
+	if math.IsNaN(score) {
+		return ErrNaN
+	}
+	s.zs.Add(member, ScoredItem[K]{Member: member, Score: score})
+	return nil
+}
+
+// Score returns the score of member.
+func (s *Scored[K]) Score(member K) (score float64, found bool) {
+	item, found := s.zs.Get(member)
+	return item.Score, found
+}
+
+// IncrBy increments the score of member by delta and returns the new score.
+// A member that does not exist is added with delta as its score. If the
+// result would be NaN, the set is left unchanged and ErrNaN is returned.
+func (s *Scored[K]) IncrBy(member K, delta float64) (float64, error) {
+	score, _ := s.Score(member)
+	score += delta
+	if math.IsNaN(score) {
+		return 0, ErrNaN
+	}
+	s.zs.Add(member, ScoredItem[K]{Member: member, Score: score})
+	return score, nil
+}
+
+// Remove removes member from the set, return true if the member existed.
+func (s *Scored[K]) Remove(member K) bool {
+	if _, found := s.zs.Get(member); !found {
+		return false
+	}
+	s.zs.Remove(member)
+	return true
+}
+
+// Rank return 1-based rank or 0 if not exist
+func (s *Scored[K]) Rank(member K, reverse bool) int {
+	return s.zs.Rank(member, reverse)
+}
+
+// Length return the member count
+func (s *Scored[K]) Length() int {
+	return s.zs.Length()
+}
+
+// Count returns the number of members with a score within [min, max].
+func (s *Scored[K]) Count(min, max ScoreBound) int {
+	greater, less := min.greater(), max.less()
+	n, minRank := s.zs.ord.findNext(func(i ScoredItem[K]) bool { return greater(i.Score) })
+	if n == nil {
+		return 0
+	}
+	_, maxRank := s.zs.ord.findPrev(func(i ScoredItem[K]) bool { return less(i.Score) })
+	if maxRank < minRank {
+		return 0
+	}
+	return maxRank - minRank + 1
+}
+
+// Range calls the iterator for every member with in index range [start, end],
+// until iterator return false. The <start> and <stop> arguments represent
+// zero-based indexes.
+func (s *Scored[K]) Range(start, end int, reverse bool, iterator ItemIterator[ScoredItem[K]]) {
+	s.zs.Range(start, end, reverse, iterator)
+}
+
+// RangeByScore calls the iterator for every member with a score within
+// [min, max], until iterator return false.
+func (s *Scored[K]) RangeByScore(min, max ScoreBound, reverse bool, iterator ItemIterator[ScoredItem[K]]) {
+	greater, less := min.greater(), max.less()
+	s.zs.RangeByScore(func(i ScoredItem[K]) bool {
+		return greater(i.Score)
+	}, func(i ScoredItem[K]) bool {
+		return less(i.Score)
+	}, reverse, iterator)
+}
@@ -0,0 +1,93 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestScored(t *testing.T) {
+	s := NewScored[string]()
+	s.ZAdd("c", 2)
+	s.ZAdd("a", 2)
+	s.ZAdd("b", 1)
+	s.ZAdd("d", 3)
+
+	if err := s.ZAdd("e", math.NaN()); err != ErrNaN {
+		t.Error("ZAdd NaN", err)
+	}
+	if s.Length() != 4 {
+		t.Error("Length error", s.Length())
+	}
+
+	// equal scores are ordered by member
+	var members []string
+	s.Range(0, -1, false, func(i ScoredItem[string], rank int) bool {
+		members = append(members, i.Member)
+		return true
+	})
+	if !reflect.DeepEqual(members, []string{"b", "a", "c", "d"}) {
+		t.Error("Range error", members)
+	}
+	if r := s.Rank("c", true); r != 2 {
+		t.Error("Rank error", r)
+	}
+
+	if score, err := s.IncrBy("b", 2.5); err != nil || score != 3.5 {
+		t.Error("IncrBy error", score, err)
+	}
+	if score, found := s.Score("b"); !found || score != 3.5 {
+		t.Error("Score error", score, found)
+	}
+	if score, err := s.IncrBy("x", -1); err != nil || score != -1 {
+		t.Error("IncrBy new member error", score, err)
+	}
+	s.ZAdd("inf", math.Inf(1))
+	if _, err := s.IncrBy("inf", math.Inf(-1)); err != ErrNaN {
+		t.Error("IncrBy NaN", err)
+	}
+	if score, _ := s.Score("inf"); !math.IsInf(score, 1) {
+		t.Error("IncrBy NaN changed score", score)
+	}
+
+	if !s.Remove("x") || s.Remove("x") {
+		t.Error("Remove error")
+	}
+}
+
+func TestScoredRangeByScore(t *testing.T) {
+	s := NewScored[int]()
+	for i := 0; i < 10; i++ {
+		s.ZAdd(i, float64(i))
+	}
+
+	tests := []struct {
+		min, max ScoreBound
+		reverse  bool
+		expect   []int
+	}{
+		{Inclusive(3), Inclusive(5), false, []int{3, 4, 5}},
+		{Exclusive(3), Inclusive(5), false, []int{4, 5}},
+		{Inclusive(3), Exclusive(5), true, []int{4, 3}},
+		{Exclusive(3), Exclusive(4), false, nil},
+		{NegInf, Exclusive(2), false, []int{0, 1}},
+		{Inclusive(8), PosInf, false, []int{8, 9}},
+		{Inclusive(5), Inclusive(3), false, nil},
+		{Inclusive(20), PosInf, false, nil},
+	}
+	for _, tt := range tests {
+		var r []int
+		s.RangeByScore(tt.min, tt.max, tt.reverse, func(i ScoredItem[int], rank int) bool {
+			r = append(r, i.Member)
+			return true
+		})
+		if !reflect.DeepEqual(r, tt.expect) {
+			t.Error("RangeByScore error", tt.min, tt.max, r, tt.expect)
+		}
+		if c := s.Count(tt.min, tt.max); c != len(tt.expect) {
+			t.Error("Count error", tt.min, tt.max, c, len(tt.expect))
+		}
+	}
+}
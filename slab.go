@@ -0,0 +1,81 @@
+//go:build go1.18
+
+package zset
+
+// DefaultSlabSize is the number of level 1 nodes allocated at once by a slab
+// free list.
+const DefaultSlabSize = 1024
+
+// slabs carves nodes and their level arrays out of large slabs, one pool per
+// level count. A freed node goes back to the pool of its level count, so its
+// level array is reused as is.
+type slabs[K comparable, T any] struct {
+	size  int                     // nodes per slab of level 1 nodes
+	nodes [][]node[K, T]          // unused nodes of the current slab, by level count - 1
+	lvls  [][]skipListLevel[K, T] // unused levels of the current slab, by level count - 1
+	free  [][]*node[K, T]         // recycled nodes, by level count - 1
+	count int                     // slabs allocated
+}
+
+// NewSlabFreeList creates a free list that allocates nodes in slabs of
+// slabSize nodes, and keeps every freed node for reuse. Nodes are grouped by
+// level count; slabs of taller nodes, which are rarer, are proportionally
+// smaller. A slab is released only when none of its nodes is referenced, so
+// memory is not returned to the runtime when a set shrinks. If slabSize <= 0,
+// DefaultSlabSize is used.
+//
+// Pass it in Options.FreeList. It cuts the heap objects of a large set by
+// about the slab size, which reduces the work of the garbage collector.
+func NewSlabFreeList[K comparable, T any](slabSize int) *FreeList[K, T] {
+	if slabSize <= 0 {
+		slabSize = DefaultSlabSize
+	}
+	return &FreeList[K, T]{slabs: &slabs[K, T]{
+		size:  slabSize,
+		nodes: make([][]node[K, T], DefaultMaxLevel),
+		lvls:  make([][]skipListLevel[K, T], DefaultMaxLevel),
+		free:  make([][]*node[K, T], DefaultMaxLevel),
+	}}
+}
+
+// newNode returns a node of lvl levels, and reports whether it was recycled.
+func (s *slabs[K, T]) newNode(lvl int) (n *node[K, T], recycled bool) {
+	i := lvl - 1
+	if free := s.free[i]; len(free) > 0 {
+		n = free[len(free)-1]
+		free[len(free)-1] = nil
+		s.free[i] = free[:len(free)-1]
+		return n, true
+	}
+	if len(s.nodes[i]) == 0 {
+		// with P = 1/4, a node has lvl levels with probability about
+		// 4^-(lvl-1), so taller pools get smaller slabs.
+		size := s.size >> (2 * uint(i))
+		if size < 4 {
+			size = 4
+		}
+		s.nodes[i] = make([]node[K, T], size)
+		s.lvls[i] = make([]skipListLevel[K, T], size*lvl)
+		s.count++
+	}
+	n = &s.nodes[i][0]
+	s.nodes[i] = s.nodes[i][1:]
+	n.level = s.lvls[i][:lvl:lvl]
+	s.lvls[i] = s.lvls[i][lvl:]
+	return n, false
+}
+
+func (s *slabs[K, T]) freeNode(n *node[K, T]) {
+	n.backward = nil
+	i := len(n.level) - 1
+	s.free[i] = append(s.free[i], n)
+}
+
+// len returns the number of recycled nodes held.
+func (s *slabs[K, T]) len() int {
+	count := 0
+	for _, free := range s.free {
+		count += len(free)
+	}
+	return count
+}
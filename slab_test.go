@@ -0,0 +1,85 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSlabFreeList(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		return a.score < b.score
+	}
+	fl := NewSlabFreeList[string, TestRank](64)
+	zs := NewWithOptions(less, Options[string, TestRank]{FreeList: fl})
+	for round := 0; round < 3; round++ {
+		for _, v := range perm(1000) {
+			zs.Add(v.member, v)
+		}
+		for _, v := range perm(1000)[:700] {
+			zs.Remove(v.member)
+		}
+		if err := zs.Validate(); err != nil {
+			t.Fatal(round, err)
+		}
+	}
+	s := zs.Stats()
+	if s.FreeListMisses+s.FreeListHits != 1000+2*700 {
+		t.Error("slab counter error", s.FreeListHits, s.FreeListMisses)
+	}
+	if s.FreeListLen != 3*700-int(s.FreeListHits) || s.FreeListSlabs == 0 {
+		t.Error("slab length error", s.FreeListLen, s.FreeListSlabs)
+	}
+	if s.FreeListMisses > 1000+DefaultMaxLevel*4 {
+		t.Error("freed nodes not reused", s.FreeListMisses)
+	}
+
+	// a node never shares its level array.
+	for x := zs.sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if len(x.level) != cap(x.level) {
+			t.Fatal("level array capacity error", len(x.level), cap(x.level))
+		}
+	}
+}
+
+func TestSlabFreeListHeapObjects(t *testing.T) {
+	const n = 100000
+	count := func(fl *FreeList[string, TestRank]) uint64 {
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{FreeList: fl, Seed: 1})
+		items := rang(n)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for _, item := range items {
+			zs.sl.insert(item.member, item)
+		}
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(zs)
+		return after.Mallocs - before.Mallocs
+	}
+	plain, slab := count(NewFreeList[string, TestRank](0)), count(NewSlabFreeList[string, TestRank](0))
+	if slab*10 > plain {
+		t.Errorf("slab allocated %d objects, plain %d", slab, plain)
+	}
+}
+
+func BenchmarkAddSlab(b *testing.B) {
+	b.StopTimer()
+	insertP := perm(benchmarkListSize)
+	b.StartTimer()
+	i := 0
+	for i < b.N {
+		tr := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{FreeList: NewSlabFreeList[string, TestRank](0)})
+		for _, item := range insertP {
+			tr.Add(item.member, item)
+			i++
+			if i >= b.N {
+				return
+			}
+		}
+	}
+}
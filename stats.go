@@ -0,0 +1,142 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// Stats describes the structure of a ZSet and counts the work done on it
+// since it was created. With BackendBTree, Level is the height of the tree,
+// MaxLevel is 0, LevelNodes[i] is the number of tree nodes at height i from
+// the leaves, and search steps count the tree nodes visited.
+type Stats struct {
+	Length   int // element count
+	Level    int // current level count of the skip list
+	MaxLevel int
+	// LevelNodes[i] is the number of nodes linked at level i, that is the
+	// nodes with more than i levels.
+	LevelNodes []int
+
+	Searches    uint64 // searches from the head of the skip list
+	SearchSteps uint64 // forward links followed by those searches
+	// AvgSearchPath is the average number of forward links followed per
+	// search.
+	AvgSearchPath float64
+	Compares      uint64 // LessFunc calls
+
+	FreeListHits   uint64 // nodes reused from the free list
+	FreeListMisses uint64 // nodes allocated because the free list was empty
+	FreeListLen    int    // nodes currently held by the free list
+	FreeListSlabs  int    // slabs allocated by a slab free list
+}
+
+// Stats returns the current statistics of the set. It walks the whole set to
+// count the nodes of each level.
+func (zs *ZSet[K, T]) Stats() Stats {
+	var s Stats
+	zs.ord.stats(&s)
+	if s.Searches > 0 {
+		s.AvgSearchPath = float64(s.SearchSteps) / float64(s.Searches)
+	}
+	return s
+}
+
+// counters count the work of the searches of a set, for Stats. Searches are
+// made by reads too, so they are updated atomically, which lets concurrent
+// readers share a set. They must be the first field of their struct, for the
+// 64-bit alignment sync/atomic requires on 32-bit platforms.
+type counters struct {
+	searches, steps, compares uint64
+}
+
+// search counts a search which followed steps links.
+func (c *counters) search(steps int) {
+	atomic.AddUint64(&c.searches, 1)
+	atomic.AddUint64(&c.steps, uint64(steps))
+}
+
+// compare counts n LessFunc calls. Searches count their calls in a local and
+// add them once, rather than paying for an atomic add per call.
+func (c *counters) compare(n int) {
+	atomic.AddUint64(&c.compares, uint64(n))
+}
+
+// load stores the counts in s.
+func (c *counters) load(s *Stats) {
+	s.Searches = atomic.LoadUint64(&c.searches)
+	s.SearchSteps = atomic.LoadUint64(&c.steps)
+	s.Compares = atomic.LoadUint64(&c.compares)
+}
+
+func (sl *skipList[K, T]) stats(s *Stats) {
+	s.Length = sl.length
+	s.Level = sl.level
+	s.MaxLevel = sl.maxLevel
+	s.LevelNodes = make([]int, sl.level)
+	sl.counters.load(s)
+	for x := sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		for i := range x.level {
+			s.LevelNodes[i]++
+		}
+	}
+	sl.freelist.stats(s)
+}
+
+func (f *FreeList[K, T]) stats(s *Stats) {
+	if f == nil {
+		return
+	}
+	s.FreeListHits, s.FreeListMisses, s.FreeListLen = f.hits, f.misses, f.len()
+	if f.slabs != nil {
+		s.FreeListSlabs = f.slabs.count
+	}
+}
+
+// PublishExpvar publishes the statistics returned by stats under name in
+// expvar. stats is called on every read of the variable, from the goroutine
+// serving it, so it must synchronize with the changes to the set.
+func PublishExpvar(name string, stats func() Stats) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return stats()
+	}))
+}
+
+// WritePrometheus writes the statistics to w in the Prometheus text format,
+// with a set label holding name.
+func (s Stats) WritePrometheus(w io.Writer, name string) error {
+	label := `set="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(name) + `"`
+	metrics := []struct {
+		name, typ, help string
+		value           uint64
+	}{
+		{"zset_length", "gauge", "Number of elements.", uint64(s.Length)},
+		{"zset_level", "gauge", "Current level count of the skip list.", uint64(s.Level)},
+		{"zset_searches_total", "counter", "Searches from the head of the skip list.", s.Searches},
+		{"zset_search_steps_total", "counter", "Forward links followed by searches.", s.SearchSteps},
+		{"zset_compares_total", "counter", "LessFunc calls.", s.Compares},
+		{"zset_freelist_hits_total", "counter", "Nodes reused from the free list.", s.FreeListHits},
+		{"zset_freelist_misses_total", "counter", "Nodes allocated because the free list was empty.", s.FreeListMisses},
+		{"zset_freelist_length", "gauge", "Nodes held by the free list.", uint64(s.FreeListLen)},
+		{"zset_freelist_slabs", "gauge", "Slabs allocated by a slab free list.", uint64(s.FreeListSlabs)},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s{%s} %v\n",
+			m.name, m.help, m.name, m.typ, m.name, label, m.value); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "# HELP zset_level_nodes Nodes linked at each level.\n# TYPE zset_level_nodes gauge\n"); err != nil {
+		return err
+	}
+	for i, n := range s.LevelNodes {
+		if _, err := fmt.Fprintf(w, "zset_level_nodes{%s,level=\"%d\"} %d\n", label, i, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
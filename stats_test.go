@@ -0,0 +1,132 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"bytes"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(1000) {
+		zs.Add(v.member, v)
+	}
+	for _, v := range perm(100) {
+		zs.Remove(v.member)
+	}
+	for _, v := range perm(10) {
+		zs.Add(v.member, v)
+	}
+
+	s := zs.Stats()
+	if s.Length != 910 || s.Level != len(s.LevelNodes) || s.LevelNodes[0] != 910 {
+		t.Error("Stats structure error", s)
+	}
+	for i := 1; i < len(s.LevelNodes); i++ {
+		if s.LevelNodes[i] > s.LevelNodes[i-1] {
+			t.Error("Stats level error", s.LevelNodes)
+		}
+	}
+	if s.FreeListMisses != 1000 || s.FreeListHits != 10 || s.FreeListLen != DefaultFreeListSize-10 {
+		t.Error("Stats free list error", s.FreeListHits, s.FreeListMisses, s.FreeListLen)
+	}
+	if s.Searches != 1110 || s.Compares == 0 || s.AvgSearchPath <= 0 {
+		t.Error("Stats counter error", s.Searches, s.Compares, s.AvgSearchPath)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WritePrometheus(&buf, `a"b`); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE zset_length gauge\n",
+		`zset_length{set="a\"b"} 910` + "\n",
+		`zset_freelist_hits_total{set="a\"b"} 10` + "\n",
+		`zset_level_nodes{set="a\"b",level="0"} 910` + "\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("WritePrometheus missing %q in\n%s", line, buf.String())
+		}
+	}
+
+	PublishExpvar("zset_test", zs.Stats)
+	if v := expvar.Get("zset_test").String(); !strings.Contains(v, `"Length":910`) {
+		t.Error("PublishExpvar error", v)
+	}
+}
+
+func TestStatsCompares(t *testing.T) {
+	for _, opts := range []Options[string, TestRank]{
+		{Backend: BackendSkipList},
+		{Backend: BackendSkipList, Finger: true},
+		{Backend: BackendBTree},
+	} {
+		calls := 0
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			calls++
+			return a.score < b.score
+		}, opts)
+		for _, v := range perm(1000) {
+			zs.Add(v.member, v)
+		}
+		// updates in place and moves.
+		for _, v := range perm(200) {
+			zs.Add(v.member, TestRank{member: v.member, score: v.score + v.score%3})
+		}
+		for _, v := range perm(100) {
+			zs.AddWithFlags(v.member, TestRank{member: v.member, score: v.score * 2}, AddGT)
+			zs.Remove(strconv.Itoa(v.score + 500))
+		}
+		zs.RankMany([]string{"1", "10", "100", "7", "700"}, false)
+		if s := zs.Stats(); s.Compares != uint64(calls) {
+			t.Error(opts.Backend, opts.Finger, "Compares error", s.Compares, calls)
+		}
+	}
+}
+
+func TestStatsConcurrentReads(t *testing.T) {
+	for _, backend := range []Backend{BackendSkipList, BackendBTree} {
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{Backend: backend})
+		for _, v := range perm(1000) {
+			zs.Add(v.member, v)
+		}
+		read := func() {
+			for i := 0; i < 100; i++ {
+				zs.Rank(strconv.Itoa(i), false)
+				zs.FindNext(func(v TestRank) bool { return v.score >= i })
+				zs.Range(i, i+10, false, func(TestRank, int) bool { return true })
+			}
+		}
+		before := zs.Stats()
+		read()
+		one := zs.Stats()
+
+		// readers share the set, as under the read lock of a sync.RWMutex.
+		// Run with -race.
+		const readers = 8
+		var wg sync.WaitGroup
+		for g := 0; g < readers; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				read()
+			}()
+		}
+		wg.Wait()
+		s := zs.Stats()
+		if s.Searches-one.Searches != readers*(one.Searches-before.Searches) ||
+			s.SearchSteps-one.SearchSteps != readers*(one.SearchSteps-before.SearchSteps) ||
+			s.Compares-one.Compares != readers*(one.Compares-before.Compares) {
+			t.Error(backend, "counters of concurrent reads", one, s)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build go1.18
+
+package zset
+
+// RangeStore returns a new ZSet holding the elements with in index range
+// [start, end], like ZRANGESTORE in redis. The <start> and <stop> arguments
+// represent zero-based indexes. The new set is built in time linear in the
+// size of the range.
+func (zs *ZSet[K, T]) RangeStore(start, end int) *ZSet[K, T] {
+	llen := zs.ord.len()
+	if start < 0 {
+		start = llen + start
+	}
+	if end < 0 {
+		end = llen + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end || start >= llen {
+		return zs.store(nil, 0)
+	}
+	if end >= llen {
+		end = llen - 1
+	}
+	return zs.store(zs.ord.getNodeByRank(start+1), end-start+1)
+}
+
+// RangeByScoreStore returns a new ZSet holding the elements within the range
+// [min, max]. If min is nil, it represents negative infinity. If max is nil,
+// it represents positive infinity. The new set is built in time linear in the
+// size of the range.
+func (zs *ZSet[K, T]) RangeByScoreStore(min, max func(i T) bool) *ZSet[K, T] {
+	minNode, minRank := zs.ord.getMinNode(), 1
+	if min != nil {
+		minNode, minRank = zs.ord.findNext(min)
+	}
+	maxRank := zs.ord.len()
+	if max != nil {
+		_, maxRank = zs.ord.findPrev(max)
+	}
+	if minNode == nil || minRank > maxRank {
+		return zs.store(nil, 0)
+	}
+	return zs.store(minNode, maxRank-minRank+1)
+}
+
+// store returns a new ZSet holding count elements starting at node n, with
+// the backend settings of zs.
+func (zs *ZSet[K, T]) store(n *node[K, T], count int) *ZSet[K, T] {
+	var ord backend[K, T]
+	if zs.compact != nil {
+		ord = newSkipList(zs.sl.less, Options[K, T]{MaxLevel: zs.compact.MaxLevel, P: zs.compact.P})
+	} else {
+		ord = zs.ord.empty()
+	}
+	ord.load(n, count)
+	out := newZSet(ord, count)
+	for x := ord.getMinNode(); x != nil; x = x.level[0].forward {
+		out.dict[x.key] = x
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRangeStore(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	if out := zs.RangeStore(0, -1); out.Length() != 0 {
+		t.Error("RangeStore on empty set", out.Length())
+	}
+	for _, v := range perm(1000) {
+		zs.Add(v.member, v)
+	}
+
+	out := zs.RangeStore(100, 199)
+	if out.Length() != 100 {
+		t.Fatal("RangeStore length error", out.Length())
+	}
+	var r []TestRank
+	out.Range(0, -1, false, func(i TestRank, _ int) bool {
+		r = append(r, i)
+		return true
+	})
+	if !reflect.DeepEqual(r, rang(200)[100:]) {
+		t.Error("RangeStore error", r)
+	}
+	for i := 100; i < 200; i++ {
+		if rank := out.Rank(strconv.Itoa(i), false); rank != i-99 {
+			t.Error("RangeStore rank error", i, rank)
+		}
+		if rank := out.Rank(strconv.Itoa(i), true); rank != 200-i {
+			t.Error("RangeStore reverse rank error", i, rank)
+		}
+	}
+
+	// the stored set is independent of the source
+	for i := 100; i < 150; i++ {
+		out.Remove(strconv.Itoa(i))
+	}
+	out.Add("1000", TestRank{member: "1000", score: 1000})
+	if out.Length() != 51 || zs.Length() != 1000 {
+		t.Error("RangeStore length error", out.Length(), zs.Length())
+	}
+	for i := 150; i < 200; i++ {
+		if rank := out.Rank(strconv.Itoa(i), false); rank != i-149 {
+			t.Error("RangeStore rank error", i, rank)
+		}
+	}
+	if rank := out.Rank("1000", false); rank != 51 {
+		t.Error("RangeStore rank error", rank)
+	}
+
+	if out := zs.RangeStore(-10, -1); out.Length() != 10 || out.Rank("990", false) != 1 {
+		t.Error("RangeStore negative index error", out.Length())
+	}
+	if out := zs.RangeStore(500, 100); out.Length() != 0 {
+		t.Error("RangeStore empty range error", out.Length())
+	}
+}
+
+func TestRangeByScoreStore(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(100) {
+		zs.Add(v.member, v)
+	}
+
+	out := zs.RangeByScoreStore(func(i TestRank) bool {
+		return i.score >= 10
+	}, func(i TestRank) bool {
+		return i.score <= 19
+	})
+	var r []TestRank
+	out.Range(0, -1, false, func(i TestRank, _ int) bool {
+		r = append(r, i)
+		return true
+	})
+	if !reflect.DeepEqual(r, rang(20)[10:]) {
+		t.Error("RangeByScoreStore error", r)
+	}
+	if item, found := out.Get("15"); !found || item.score != 15 {
+		t.Error("RangeByScoreStore get error", item, found)
+	}
+
+	if out := zs.RangeByScoreStore(nil, nil); out.Length() != 100 {
+		t.Error("RangeByScoreStore error", out.Length())
+	}
+	if out := zs.RangeByScoreStore(func(i TestRank) bool {
+		return i.score >= 200
+	}, nil); out.Length() != 0 {
+		t.Error("RangeByScoreStore error", out.Length())
+	}
+}
@@ -0,0 +1,401 @@
+//go:build go1.18
+
+package zset
+
+import "sort"
+
+// Tx buffers the changes of a set made within a transaction. Its reads see
+// the buffered changes. It must not be used after the transaction ends.
+type Tx[K comparable, T any] struct {
+	zs *ZSet[K, T]
+	// ops are the buffered changes, applied in order on commit.
+	ops []txOp[K, T]
+	// writes is the index in ops of the last buffered change of every key.
+	writes map[K]int
+}
+
+// txOp is a buffered Add, or a Remove if removed.
+type txOp[K comparable, T any] struct {
+	key     K
+	item    T
+	removed bool
+}
+
+// TxGroup is a transaction over several sets, possibly of different types,
+// which are bound to it with Bind.
+type TxGroup struct {
+	txs []interface {
+		commit()
+		release()
+	}
+	done bool
+}
+
+// RunTxn runs fn in a new transaction group. If fn returns nil, the changes of
+// all the sets bound to the group are applied, otherwise they are discarded
+// and the error of fn is returned.
+//
+// The observers of the sets, such as Subscribe and WatchTop, are notified
+// once the changes of all the sets are applied: they see the sets as the
+// transaction leaves them, and receive the events of the changes in order,
+// with the ranks of each change when it was applied.
+func RunTxn(fn func(g *TxGroup) error) error {
+	g := &TxGroup{}
+	defer func() {
+		g.done = true
+	}()
+	if err := fn(g); err != nil {
+		return err
+	}
+	for _, tx := range g.txs {
+		tx.commit()
+	}
+	for _, tx := range g.txs {
+		tx.release()
+	}
+	return nil
+}
+
+// Bind returns the transaction of zs in group g, creating it on the first
+// call.
+func Bind[K comparable, T any](g *TxGroup, zs *ZSet[K, T]) *Tx[K, T] {
+	if g.done {
+		panic("zset: Bind on a finished transaction")
+	}
+	for _, tx := range g.txs {
+		if tx, ok := tx.(*Tx[K, T]); ok && tx.zs == zs {
+			return tx
+		}
+	}
+	tx := &Tx[K, T]{zs: zs, writes: make(map[K]int)}
+	g.txs = append(g.txs, tx)
+	return tx
+}
+
+// Txn runs fn in a transaction of the set. If fn returns nil, its changes are
+// applied, otherwise they are discarded and the error of fn is returned. See
+// RunTxn for transactions over several sets.
+func (zs *ZSet[K, T]) Txn(fn func(tx *Tx[K, T]) error) error {
+	return RunTxn(func(g *TxGroup) error {
+		return fn(Bind(g, zs))
+	})
+}
+
+// Add buffers an Add of the set.
+func (tx *Tx[K, T]) Add(key K, item T) {
+	tx.writes[key] = len(tx.ops)
+	tx.ops = append(tx.ops, txOp[K, T]{key: key, item: item})
+}
+
+// Remove buffers a Remove of the set.
+func (tx *Tx[K, T]) Remove(key K) {
+	tx.writes[key] = len(tx.ops)
+	tx.ops = append(tx.ops, txOp[K, T]{key: key, removed: true})
+}
+
+// commit applies the buffered changes to the set, holding back its events
+// until release.
+func (tx *Tx[K, T]) commit() {
+	tx.zs.holdEvents()
+	for _, op := range tx.ops {
+		if op.removed {
+			tx.zs.Remove(op.key)
+		} else {
+			tx.zs.Add(op.key, op.item)
+		}
+	}
+}
+
+// release delivers the events of the changes applied by commit.
+func (tx *Tx[K, T]) release() {
+	tx.zs.releaseEvents()
+}
+
+// Get is like ZSet.Get, with the buffered changes.
+func (tx *Tx[K, T]) Get(key K) (item T, found bool) {
+	if i, ok := tx.writes[key]; ok {
+		op := tx.ops[i]
+		if op.removed {
+			return
+		}
+		return op.item, true
+	}
+	return tx.zs.Get(key)
+}
+
+// Length is like ZSet.Length, with the buffered changes.
+func (tx *Tx[K, T]) Length() int {
+	n := tx.zs.Length()
+	for key, i := range tx.writes {
+		_, found := tx.zs.Get(key)
+		if removed := tx.ops[i].removed; found && removed {
+			n--
+		} else if !found && !removed {
+			n++
+		}
+	}
+	return n
+}
+
+// Rank is like ZSet.Rank, with the buffered changes. It costs a search and a
+// comparison per key changed by the transaction. Items that are equal under
+// the LessFunc may be ranked in another order than after commit.
+func (tx *Tx[K, T]) Rank(key K, reverse bool) int {
+	item, found := tx.Get(key)
+	if !found {
+		return 0
+	}
+	// count the elements ranked before item: those of the set which are
+	// less than item and not changed, then the buffered ones.
+	n, rank := tx.zs.ord.findNext(func(i T) bool {
+		return !tx.zs.ord.lessThan(i, item)
+	})
+	if n == nil {
+		rank = tx.zs.ord.len() + 1
+	}
+	for k, i := range tx.writes {
+		op := tx.ops[i]
+		if n := tx.zs.lookup(k); n != nil && tx.zs.ord.lessThan(n.item, item) {
+			rank--
+		}
+		if k != key && !op.removed && tx.zs.ord.lessThan(op.item, item) {
+			rank++
+		}
+	}
+	if reverse {
+		return tx.Length() - rank + 1
+	}
+	return rank
+}
+
+// Range is like ZSet.Range, with the buffered changes. It costs a search per
+// key changed by the transaction, then a walk of the range. The buffered items
+// are ranked before the items of the set equal to them under the LessFunc.
+func (tx *Tx[K, T]) Range(start, end int, reverse bool, iterator ItemIterator[T]) {
+	v := tx.view()
+	if start < 0 {
+		start = v.length + start
+	}
+	if end < 0 {
+		end = v.length + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end || start >= v.length {
+		return
+	}
+	if end >= v.length {
+		end = v.length - 1
+	}
+	if reverse {
+		v.each(v.length-1-end, v.length-1-start, true, iterator)
+	} else {
+		v.each(start, end, false, iterator)
+	}
+}
+
+// RangeByScore is like ZSet.RangeByScore, with the buffered changes. It costs
+// two searches and a call of min and max per key changed by the transaction,
+// then a walk of the range.
+func (tx *Tx[K, T]) RangeByScore(min, max func(i T) bool, reverse bool, iterator ItemIterator[T]) {
+	v := tx.view()
+	ord := tx.zs.ord
+	first, last := 0, v.length-1
+	if min != nil {
+		// count the items for which min is false.
+		n, rank := ord.findNext(min)
+		if n == nil {
+			rank = ord.len() + 1
+		}
+		first = rank - 1 - sort.SearchInts(v.changed, rank)
+		for _, a := range v.adds {
+			if !min(a.item) {
+				first++
+			}
+		}
+	}
+	if max != nil {
+		// count the items for which max is true.
+		_, rank := ord.findPrev(max)
+		last = rank - sort.SearchInts(v.changed, rank+1) - 1
+		for _, a := range v.adds {
+			if max(a.item) {
+				last++
+			}
+		}
+	}
+	if first <= last {
+		v.each(first, last, reverse, iterator)
+	}
+}
+
+// txView is the order of a set with the buffered changes of a transaction: the
+// elements of the set whose key is not changed, merged with the buffered adds.
+type txView[K comparable, T any] struct {
+	tx *Tx[K, T]
+	// adds are the buffered adds, in order.
+	adds []txAdd[T]
+	// changed are the ranks in the set of the elements whose key is changed,
+	// in order.
+	changed []int
+	length  int
+}
+
+// txAdd is a buffered add, which goes before the element of the set of rank
+// before, or after the last element if before is the length plus 1.
+type txAdd[T any] struct {
+	item   T
+	before int
+	op     int // index in the ops of the transaction
+}
+
+// view returns the order of the set with the buffered changes.
+func (tx *Tx[K, T]) view() *txView[K, T] {
+	ord := tx.zs.ord
+	v := &txView[K, T]{tx: tx}
+	for key, i := range tx.writes {
+		if n := tx.zs.lookup(key); n != nil {
+			v.changed = append(v.changed, ord.rank(n, false))
+		}
+		op := tx.ops[i]
+		if op.removed {
+			continue
+		}
+		// as Add, go before the items equal to op.item.
+		n, rank := ord.findNext(func(i T) bool {
+			return !ord.lessThan(i, op.item)
+		})
+		if n == nil {
+			rank = ord.len() + 1
+		}
+		v.adds = append(v.adds, txAdd[T]{item: op.item, before: rank, op: i})
+	}
+	sort.Ints(v.changed)
+	sort.Slice(v.adds, func(i, j int) bool {
+		a, b := v.adds[i], v.adds[j]
+		if a.before != b.before {
+			return a.before < b.before
+		}
+		if ord.lessThan(a.item, b.item) {
+			return true
+		}
+		if ord.lessThan(b.item, a.item) {
+			return false
+		}
+		// the later add goes before the equal items.
+		return a.op > b.op
+	})
+	v.length = ord.len() - len(v.changed) + len(v.adds)
+	return v
+}
+
+// seek returns the position of index pos of the view, which must be less than
+// its length: the element there is adds[j] if it goes before rank r, and the
+// element of rank r of the set otherwise. It walks the changes, not the set.
+func (v *txView[K, T]) seek(pos int) (r, j int) {
+	r, i := 1, 0
+	for {
+		for j < len(v.adds) && v.adds[j].before == r {
+			if pos == 0 {
+				return r, j
+			}
+			pos--
+			j++
+		}
+		if i < len(v.changed) && v.changed[i] == r {
+			i++
+			r++
+			continue
+		}
+		// the elements of ranks [r, next) of the set are not changed.
+		next := v.tx.zs.ord.len() + 1
+		if i < len(v.changed) && v.changed[i] < next {
+			next = v.changed[i]
+		}
+		if j < len(v.adds) && v.adds[j].before < next {
+			next = v.adds[j].before
+		}
+		if pos < next-r {
+			return r + pos, j
+		}
+		pos -= next - r
+		r = next
+	}
+}
+
+// each calls iterator for the items of index range [first, last] of the view,
+// from last down to first if reverse, and their ranks in that direction.
+func (v *txView[K, T]) each(first, last int, reverse bool, iterator ItemIterator[T]) {
+	ord := v.tx.zs.ord
+	pos := first
+	if reverse {
+		pos = last
+	}
+	// the item at pos is adds[j] if it goes before rank r, and the element
+	// of rank r of the set, x, otherwise. x is nil after the last element.
+	r, j := v.seek(pos)
+	x := ord.getNodeByRank(r)
+	for {
+		isAdd := j < len(v.adds) && v.adds[j].before <= r
+		var item T
+		if isAdd {
+			item = v.adds[j].item
+		} else {
+			item = x.item
+		}
+		rank := pos + 1
+		if reverse {
+			rank = v.length - pos
+		}
+		if !iterator(item, rank) {
+			return
+		}
+		if reverse {
+			if pos == first {
+				return
+			}
+			pos--
+			// step back to the previous add going before rank r, or to
+			// the previous unchanged element.
+			for {
+				if j > 0 && v.adds[j-1].before == r {
+					j--
+					break
+				}
+				r--
+				if x == nil {
+					x = ord.getMaxNode()
+				} else {
+					x = x.backward
+				}
+				if !v.changedNode(x) {
+					break
+				}
+			}
+		} else {
+			if pos == last {
+				return
+			}
+			pos++
+			if isAdd {
+				j++
+			} else {
+				x = x.level[0].forward
+				r++
+			}
+			// step over the changed elements, unless an add goes first.
+			for !(j < len(v.adds) && v.adds[j].before <= r) && v.changedNode(x) {
+				x = x.level[0].forward
+				r++
+			}
+		}
+	}
+}
+
+// changedNode reports whether the key of node x is changed by the
+// transaction.
+func (v *txView[K, T]) changedNode(x *node[K, T]) bool {
+	_, changed := v.tx.writes[x.key]
+	return changed
+}
@@ -0,0 +1,245 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTxn(t *testing.T) {
+	less := func(a, b TestRank) bool {
+		if a.score == b.score {
+			return a.member < b.member
+		}
+		return a.score < b.score
+	}
+	items := func(zs *ZSet[string, TestRank]) (out []TestRank) {
+		zs.Range(0, -1, false, func(v TestRank, _ int) bool {
+			out = append(out, v)
+			return true
+		})
+		return
+	}
+	zs := New[string](less)
+	for _, v := range perm(100) {
+		zs.Add(v.member, v)
+	}
+	for round := 0; round < 20; round++ {
+		// ref gets the changes of the transaction right away.
+		ref := New[string](less)
+		for _, v := range items(zs) {
+			ref.Add(v.member, v)
+		}
+		before := items(zs)
+		fail := round%2 == 1
+		errFail := errors.New("fail")
+		err := zs.Txn(func(tx *Tx[string, TestRank]) error {
+			for i := 0; i < 50; i++ {
+				key := strconv.Itoa(rand.Intn(150))
+				if rand.Intn(3) == 0 {
+					tx.Remove(key)
+					ref.Remove(key)
+				} else {
+					v := TestRank{member: key, score: rand.Intn(200)}
+					tx.Add(key, v)
+					ref.Add(key, v)
+				}
+				if tx.Length() != ref.Length() {
+					t.Fatal("Length error", tx.Length(), ref.Length())
+				}
+				for j := 0; j < 5; j++ {
+					key := strconv.Itoa(rand.Intn(150))
+					v, ok := tx.Get(key)
+					if w, wok := ref.Get(key); v != w || ok != wok {
+						t.Fatal("Get error", key, v, w)
+					}
+					for _, reverse := range []bool{false, true} {
+						if got, want := tx.Rank(key, reverse), ref.Rank(key, reverse); got != want {
+							t.Fatal("Rank error", key, reverse, got, want)
+						}
+					}
+				}
+			}
+			// ranged reads, with indexes and bounds around the ends.
+			for j := 0; j < 20; j++ {
+				start, end := rand.Intn(300)-150, rand.Intn(300)-150
+				min, max := rand.Intn(220)-10, rand.Intn(220)-10
+				for _, reverse := range []bool{false, true} {
+					type visit struct {
+						v    TestRank
+						rank int
+					}
+					var got, want []visit
+					tx.Range(start, end, reverse, func(v TestRank, rank int) bool {
+						got = append(got, visit{v, rank})
+						return true
+					})
+					ref.Range(start, end, reverse, func(v TestRank, rank int) bool {
+						want = append(want, visit{v, rank})
+						return true
+					})
+					if !reflect.DeepEqual(got, want) {
+						t.Fatal("Range error", start, end, reverse, got, want)
+					}
+					got, want = got[:0], want[:0]
+					minFn := func(i TestRank) bool { return i.score >= min }
+					maxFn := func(i TestRank) bool { return i.score <= max }
+					if j == 0 {
+						minFn, maxFn = nil, nil
+					}
+					tx.RangeByScore(minFn, maxFn, reverse, func(v TestRank, rank int) bool {
+						got = append(got, visit{v, rank})
+						return len(got) < 30
+					})
+					ref.RangeByScore(minFn, maxFn, reverse, func(v TestRank, rank int) bool {
+						want = append(want, visit{v, rank})
+						return len(want) < 30
+					})
+					if !reflect.DeepEqual(got, want) {
+						t.Fatal("RangeByScore error", min, max, reverse, got, want)
+					}
+				}
+			}
+			if !reflect.DeepEqual(items(zs), before) {
+				t.Fatal("changes applied before commit")
+			}
+			if fail {
+				return errFail
+			}
+			return nil
+		})
+		if fail {
+			if err != errFail || !reflect.DeepEqual(items(zs), before) {
+				t.Fatal("rollback error", err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(items(zs), items(ref)) {
+			t.Fatal("commit error", err)
+		}
+		if err := zs.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTxnTies(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	zs.Add("a", TestRank{member: "a", score: 1})
+	zs.Add("x", TestRank{member: "x", score: 2})
+	keys := func(each func(iterator ItemIterator[TestRank])) (out []string) {
+		each(func(v TestRank, _ int) bool {
+			out = append(out, v.member)
+			return true
+		})
+		return
+	}
+	var during []string
+	zs.Txn(func(tx *Tx[string, TestRank]) error {
+		for _, key := range []string{"b", "c", "d"} {
+			tx.Add(key, TestRank{member: key, score: 1})
+		}
+		during = keys(func(iterator ItemIterator[TestRank]) {
+			tx.Range(0, -1, false, iterator)
+		})
+		return nil
+	})
+	// as Add, the buffered items go before the items equal to them.
+	after := keys(func(iterator ItemIterator[TestRank]) {
+		zs.Range(0, -1, false, iterator)
+	})
+	if !reflect.DeepEqual(during, after) {
+		t.Error("Range error", during, after)
+	}
+}
+
+func TestTxnEvents(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range rang(10) {
+		zs.Add(v.member, v)
+	}
+	var events []Event[string, TestRank]
+	zs.Subscribe(func(e Event[string, TestRank]) {
+		// the whole transaction is applied before the events.
+		if _, found := zs.Get("0"); found || zs.Length() != 11 {
+			t.Error("event delivered before the end of the commit", e)
+		}
+		events = append(events, e)
+	})
+	ch, cancel := zs.WatchBottom(3)
+	defer cancel()
+	<-ch
+	zs.Txn(func(tx *Tx[string, TestRank]) error {
+		tx.Remove("0")
+		tx.Add("10", TestRank{member: "10", score: 10})
+		tx.Add("11", TestRank{member: "11", score: 11})
+		tx.Remove("5")
+		tx.Add("5", TestRank{member: "5", score: -1})
+		return nil
+	})
+	want := []Event[string, TestRank]{
+		{Type: EventRemove, Key: "0", Old: TestRank{"0", 0}, OldRank: 1},
+		{Type: EventAdd, Key: "10", New: TestRank{"10", 10}, NewRank: 10},
+		{Type: EventAdd, Key: "11", New: TestRank{"11", 11}, NewRank: 11},
+		{Type: EventRemove, Key: "5", Old: TestRank{"5", 5}, OldRank: 5},
+		{Type: EventAdd, Key: "5", New: TestRank{"5", -1}, NewRank: 1},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Error("events error", events, want)
+	}
+	// the ranks of the events are those of the time of the change.
+	if w := <-ch; !reflect.DeepEqual(w, []TestRank{{"11", 11}, {"10", 10}, {"9", 9}}) {
+		t.Error("WatchBottom error", w)
+	}
+}
+
+func TestTxGroup(t *testing.T) {
+	a := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	b := New[int](func(a, b int) bool {
+		return a < b
+	})
+	a.Add("x", TestRank{member: "x", score: 1})
+	for _, fail := range []bool{true, false} {
+		var g *TxGroup
+		err := RunTxn(func(tg *TxGroup) error {
+			g = tg
+			Bind(g, a).Remove("x")
+			Bind(g, a).Add("y", TestRank{member: "y", score: 2})
+			Bind(g, b).Add(1, 10)
+			if Bind(g, a) != Bind(g, a) {
+				t.Error("Bind returned another transaction")
+			}
+			if fail {
+				return errors.New("fail")
+			}
+			return nil
+		})
+		_, x := a.Get("x")
+		_, y := a.Get("y")
+		_, one := b.Get(1)
+		if fail && (err == nil || !x || y || one) {
+			t.Error("rollback error", err, x, y, one)
+		}
+		if !fail && (err != nil || x || !y || !one) {
+			t.Error("commit error", err, x, y, one)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Bind on a finished transaction did not panic")
+				}
+			}()
+			Bind(g, a)
+		}()
+	}
+}
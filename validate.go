@@ -0,0 +1,122 @@
+//go:build go1.18
+
+package zset
+
+import "fmt"
+
+// Validate checks the internal invariants of the set and returns an error
+// describing the first violation found, or nil. It walks the whole set, so it
+// is meant for tests and debugging, e.g. to detect an inconsistent LessFunc.
+func (zs *ZSet[K, T]) Validate() error {
+	ranks, err := zs.ord.validate()
+	if err != nil {
+		return err
+	}
+	if zs.compact != nil {
+		if zs.dict != nil || zs.sl.maxLevel != 1 {
+			return fmt.Errorf("zset: compact set has a dict or %d levels", zs.sl.maxLevel)
+		}
+		if zs.sl.length > zs.compact.CompactThreshold {
+			return fmt.Errorf("zset: compact set has %d elements, threshold is %d", zs.sl.length, zs.compact.CompactThreshold)
+		}
+		keys := make(map[K]bool, zs.sl.length)
+		for x := zs.sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+			if keys[x.key] {
+				return fmt.Errorf("zset: key %v appears twice", x.key)
+			}
+			keys[x.key] = true
+		}
+		return nil
+	}
+	if len(zs.dict) != zs.ord.len() {
+		return fmt.Errorf("zset: dict has %d keys, list has %d nodes", len(zs.dict), zs.ord.len())
+	}
+	for key, n := range zs.dict {
+		if _, ok := ranks[n]; !ok {
+			return fmt.Errorf("zset: node of key %v is not in the list", key)
+		}
+		if n.key != key {
+			return fmt.Errorf("zset: node of key %v has key %v", key, n.key)
+		}
+	}
+	return nil
+}
+
+// validate checks the invariants of the skip list, and returns the 1-based
+// rank of every node.
+func (sl *skipList[K, T]) validate() (map[*node[K, T]]int, error) {
+	if sl.level < 1 || sl.level > sl.maxLevel || len(sl.header.level) != sl.maxLevel {
+		return nil, fmt.Errorf("zset: level %d out of [1, %d]", sl.level, sl.maxLevel)
+	}
+	if sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		return nil, fmt.Errorf("zset: level %d is empty", sl.level-1)
+	}
+	for i := sl.level; i < sl.maxLevel; i++ {
+		if sl.header.level[i].forward != nil {
+			return nil, fmt.Errorf("zset: header links level %d above level count %d", i, sl.level)
+		}
+	}
+
+	// level 0 gives the ranks, the order and the backward links.
+	ranks := map[*node[K, T]]int{sl.header: 0}
+	heights := make([]int, sl.level) // nodes with more than i levels
+	var prev *node[K, T]
+	for x := sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		rank := len(ranks)
+		if _, ok := ranks[x]; ok {
+			return nil, fmt.Errorf("zset: level 0 has a cycle at rank %d", rank)
+		}
+		ranks[x] = rank
+		if len(x.level) < 1 || len(x.level) > sl.level {
+			return nil, fmt.Errorf("zset: node at rank %d has %d levels", rank, len(x.level))
+		}
+		for i := range x.level {
+			heights[i]++
+		}
+		if x.backward != prev {
+			return nil, fmt.Errorf("zset: wrong backward link at rank %d", rank)
+		}
+		if prev != nil && sl.less(x.item, prev.item) {
+			return nil, fmt.Errorf("zset: node at rank %d is less than its predecessor", rank)
+		}
+		prev = x
+	}
+	length := len(ranks) - 1
+	if length != sl.length {
+		return nil, fmt.Errorf("zset: length is %d, level 0 has %d nodes", sl.length, length)
+	}
+	if sl.tail != prev {
+		return nil, fmt.Errorf("zset: tail is not the last node")
+	}
+
+	// every level must link nodes in rank order, with spans summing the
+	// ranks skipped. Links to the end span the remaining nodes.
+	for i := 0; i < sl.level; i++ {
+		linked := 0
+		for x := sl.header; x != nil; x = x.level[i].forward {
+			if len(x.level) <= i {
+				return nil, fmt.Errorf("zset: node at rank %d linked at level %d", ranks[x], i)
+			}
+			rank, ok := ranks[x]
+			if !ok {
+				return nil, fmt.Errorf("zset: level %d links a node not in level 0", i)
+			}
+			next := length
+			if y := x.level[i].forward; y != nil {
+				if next, ok = ranks[y]; !ok || next <= rank {
+					return nil, fmt.Errorf("zset: level %d is out of order at rank %d", i, rank)
+				}
+			}
+			if span := x.level[i].span; span != next-rank {
+				return nil, fmt.Errorf("zset: span of rank %d at level %d is %d, want %d", rank, i, span, next-rank)
+			}
+			if x != sl.header {
+				linked++
+			}
+		}
+		if linked != heights[i] {
+			return nil, fmt.Errorf("zset: level %d links %d of %d nodes", i, linked, heights[i])
+		}
+	}
+	return ranks, nil
+}
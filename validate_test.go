@@ -0,0 +1,83 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5000; i++ {
+		key := strconv.Itoa(rand.Intn(300))
+		switch rand.Intn(3) {
+		case 0:
+			zs.Remove(key)
+		default:
+			zs.Add(key, TestRank{member: key, score: rand.Intn(100)})
+		}
+		if err := zs.Validate(); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	if err := zs.RangeStore(10, 200).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for zs.Length() > 0 {
+		v, _ := zs.FindNext(func(TestRank) bool { return true })
+		zs.Remove(v.member)
+	}
+	if err := zs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateCorrupted(t *testing.T) {
+	newSet := func() *ZSet[string, TestRank] {
+		zs := New[string, TestRank](func(a, b TestRank) bool {
+			return a.score < b.score
+		})
+		for _, v := range perm(100) {
+			zs.Add(v.member, v)
+		}
+		return zs
+	}
+
+	corruptions := map[string]func(zs *ZSet[string, TestRank]){
+		"order": func(zs *ZSet[string, TestRank]) {
+			zs.dict["50"].item.score = 1000
+		},
+		"span": func(zs *ZSet[string, TestRank]) {
+			zs.sl.header.level[0].span++
+		},
+		"backward": func(zs *ZSet[string, TestRank]) {
+			zs.dict["50"].backward = nil
+		},
+		"tail": func(zs *ZSet[string, TestRank]) {
+			zs.sl.tail = zs.dict["50"]
+		},
+		"length": func(zs *ZSet[string, TestRank]) {
+			zs.sl.length--
+		},
+		"dict": func(zs *ZSet[string, TestRank]) {
+			zs.dict["50"] = zs.dict["51"]
+		},
+		"level": func(zs *ZSet[string, TestRank]) {
+			zs.sl.level++
+		},
+	}
+	for name, corrupt := range corruptions {
+		zs := newSet()
+		corrupt(zs)
+		if err := zs.Validate(); err == nil {
+			t.Error("corruption not detected", name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+//go:build go1.18
+
+package zset
+
+// WatchTop returns a channel which receives the items with in ranks [1, n],
+// first when the watch is created and then each time a change of the set
+// alters the membership or the order of that window. Only the latest window is
+// kept if the receiver falls behind. The returned function stops the watch and
+// closes the channel; it must not be called concurrently with changes to the
+// set. WatchTop panics if n is not positive.
+func (zs *ZSet[K, T]) WatchTop(n int) (<-chan []T, func()) {
+	return zs.watch(n, false)
+}
+
+// WatchBottom is like WatchTop for the n items with the highest ranks, which
+// are received from the highest rank down.
+func (zs *ZSet[K, T]) WatchBottom(n int) (<-chan []T, func()) {
+	return zs.watch(n, true)
+}
+
+func (zs *ZSet[K, T]) watch(n int, reverse bool) (<-chan []T, func()) {
+	if n <= 0 {
+		panic("zset: watch of a non-positive number of items")
+	}
+	ch := make(chan []T, 1)
+	send := func() {
+		window := make([]T, 0, n)
+		zs.Range(0, n-1, reverse, func(i T, _ int) bool {
+			window = append(window, i)
+			return true
+		})
+		// drop the window the receiver has not taken yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- window
+	}
+	send()
+
+	cancel := zs.subscribe(func(e Event[K, T], length int) {
+		oldRank, newRank := e.OldRank, e.NewRank
+		if reverse {
+			if e.Type == EventRemove {
+				oldRank = length + 2 - oldRank
+			} else if e.Type == EventUpdate {
+				oldRank = length + 1 - oldRank
+			}
+			if e.Type != EventRemove {
+				newRank = length + 1 - newRank
+			}
+		}
+		if oldRank == newRank {
+			// updated without moving.
+			return
+		}
+		if (oldRank > 0 && oldRank <= n) || (newRank > 0 && newRank <= n) {
+			send()
+		}
+	})
+	var closed bool
+	return ch, func() {
+		if !closed {
+			closed = true
+			cancel()
+			close(ch)
+		}
+	}
+}
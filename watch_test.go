@@ -0,0 +1,128 @@
+//go:build go1.18
+
+package zset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatchTop(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range rang(10) {
+		zs.Add(v.member, v)
+	}
+
+	ch, cancel := zs.WatchTop(3)
+	recv := func() []int {
+		select {
+		case window := <-ch:
+			scores := []int{}
+			for _, i := range window {
+				scores = append(scores, i.score)
+			}
+			return scores
+		default:
+			return nil
+		}
+	}
+	if w := recv(); !reflect.DeepEqual(w, []int{0, 1, 2}) {
+		t.Error("initial window error", w)
+	}
+
+	zs.Add("a", TestRank{member: "a", score: 100}) // below the window
+	zs.Remove("5")
+	zs.Add("9", TestRank{member: "9", score: 50}) // moved below the window
+	if w := recv(); w != nil {
+		t.Error("unexpected window", w)
+	}
+
+	zs.Add("b", TestRank{member: "b", score: -1})
+	if w := recv(); !reflect.DeepEqual(w, []int{-1, 0, 1}) {
+		t.Error("window error", w)
+	}
+	zs.Remove("0")
+	zs.Add("1", TestRank{member: "1", score: 200}) // moved out of the window
+	if w := recv(); !reflect.DeepEqual(w, []int{-1, 2, 3}) {
+		t.Error("latest window error", w)
+	}
+
+	cancel()
+	zs.Remove("b")
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed")
+	}
+}
+
+func TestWatchBottom(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range rang(10) {
+		zs.Add(v.member, v)
+	}
+
+	ch, cancel := zs.WatchBottom(2)
+	defer cancel()
+	recv := func() []int {
+		select {
+		case window := <-ch:
+			scores := []int{}
+			for _, i := range window {
+				scores = append(scores, i.score)
+			}
+			return scores
+		default:
+			return nil
+		}
+	}
+	if w := recv(); !reflect.DeepEqual(w, []int{9, 8}) {
+		t.Error("initial window error", w)
+	}
+
+	zs.Add("a", TestRank{member: "a", score: -1})
+	zs.Remove("3")
+	zs.Add("0", TestRank{member: "0", score: 5})
+	if w := recv(); w != nil {
+		t.Error("unexpected window", w)
+	}
+
+	zs.Remove("9")
+	if w := recv(); !reflect.DeepEqual(w, []int{8, 7}) {
+		t.Error("window error", w)
+	}
+	zs.Add("1", TestRank{member: "1", score: 20})
+	if w := recv(); !reflect.DeepEqual(w, []int{20, 8}) {
+		t.Error("window error", w)
+	}
+	zs.Add("1", TestRank{member: "1", score: 30}) // updated without moving
+	if w := recv(); w != nil {
+		t.Error("unexpected window", w)
+	}
+}
+
+func TestWatchInvalid(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range rang(10) {
+		zs.Add(v.member, v)
+	}
+	for _, n := range []int{0, -1} {
+		for _, watch := range []func(int) (<-chan []TestRank, func()){zs.WatchTop, zs.WatchBottom} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("watch of n accepted", n)
+					}
+				}()
+				watch(n)
+			}()
+		}
+	}
+	if len(zs.observers) != 0 {
+		t.Error("invalid watch subscribed")
+	}
+}
@@ -6,6 +6,7 @@ package zset
 
 import (
 	"math/rand"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -88,14 +89,54 @@ type skipList struct {
 	length       int
 	level        int // current level count
 	maxLevel     int
+	p            float64 // probability of one more level
 	freelist     *FreeList
 	random       *rand.Rand
 }
 
-// newSkipList creates a skip list
-func newSkipList(maxLevel int) *skipList {
-	if maxLevel < DefaultMaxLevel {
-		panic("maxLevel must < 32")
+// Options configures a ZSet created by NewWithOptions. The zero value of every
+// field selects the default used by New.
+type Options struct {
+	// Seed seeds the random generator of node levels, so that the same
+	// sequence of operations builds the same layout. 0 seeds from the clock.
+	Seed int64
+	// P is the probability of a node having one more level, in (0, 1).
+	// Default DefaultP.
+	P float64
+	// MaxLevel is the maximum level count of a node, in [1, DefaultMaxLevel].
+	// Default DefaultMaxLevel.
+	MaxLevel int
+	// FreeList is used to recycle the nodes of the set. It may be shared by
+	// several sets that are not used concurrently. Default a new free list of
+	// DefaultFreeListSize nodes.
+	FreeList *FreeList
+	// InitialCapacity is a hint for the number of elements of the set.
+	InitialCapacity int
+}
+
+// newSkipList creates a skip list configured by opts.
+func newSkipList(opts Options) *skipList {
+	maxLevel := opts.MaxLevel
+	if maxLevel == 0 {
+		maxLevel = DefaultMaxLevel
+	}
+	if maxLevel < 1 || maxLevel > DefaultMaxLevel {
+		panic("zset: MaxLevel must be in [1, 32]")
+	}
+	p := opts.P
+	if p == 0 {
+		p = DefaultP
+	}
+	if !(p > 0 && p < 1) {
+		panic("zset: P must be in (0, 1)")
+	}
+	freelist := opts.FreeList
+	if freelist == nil {
+		freelist = NewFreeList(DefaultFreeListSize)
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
 	return &skipList{
 		level: 1,
@@ -103,8 +144,9 @@ func newSkipList(maxLevel int) *skipList {
 			level: make([]skipListLevel, maxLevel),
 		},
 		maxLevel: maxLevel,
-		freelist: NewFreeList(DefaultFreeListSize),
-		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		p:        p,
+		freelist: freelist,
+		random:   rand.New(rand.NewSource(seed)),
 	}
 }
 
@@ -165,10 +207,8 @@ func (sl *skipList) insert(item Item) *node {
 	return x
 }
 
-// delete element
-func (sl *skipList) delete(n *node) Item {
-	var preAlloc [DefaultMaxLevel]*node // [0...list.maxLevel)
-	update := preAlloc[:sl.maxLevel]
+// findUpdate fills update with the predecessors of node n at every level.
+func (sl *skipList) findUpdate(n *node, update []*node) {
 	x := sl.header
 	for i := sl.level - 1; i >= 0; i-- {
 		for y := x.level[i].forward; y != nil && y.item.Less(n.item); y = x.level[i].forward {
@@ -176,30 +216,43 @@ func (sl *skipList) delete(n *node) Item {
 		}
 		update[i] = x
 	}
-	x = x.level[0].forward
-	if x != nil && !n.item.Less(x.item) {
-		for i := 0; i < sl.level; i++ {
-			if update[i].level[i].forward == x {
-				update[i].level[i].span += x.level[i].span - 1
-				update[i].level[i].forward = x.level[i].forward
-			} else {
-				update[i].level[i].span--
-			}
-		}
-		for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
-			sl.level--
+	// skip the items which are equal to n but ranked before it.
+	for y := x.level[0].forward; y != nil && y != n && !n.item.Less(y.item); y = y.level[0].forward {
+		for i := 0; i < len(y.level); i++ {
+			update[i] = y
 		}
-		if x.level[0].forward == nil {
-			sl.tail = x.backward
+	}
+}
+
+// delete element
+func (sl *skipList) delete(n *node) Item {
+	var preAlloc [DefaultMaxLevel]*node // [0...list.maxLevel)
+	update := preAlloc[:sl.maxLevel]
+	sl.findUpdate(n, update)
+	x := update[0].level[0].forward
+	if x != n {
+		return nil
+	}
+	for i := 0; i < sl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
 		} else {
-			x.level[0].forward.backward = x.backward
+			update[i].level[i].span--
 		}
-		removeItem := x.item
-		sl.freelist.freeNode(x)
-		sl.length--
-		return removeItem
 	}
-	return nil
+	for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		sl.level--
+	}
+	if x.level[0].forward == nil {
+		sl.tail = x.backward
+	} else {
+		x.level[0].forward.backward = x.backward
+	}
+	removeItem := x.item
+	sl.freelist.freeNode(x)
+	sl.length--
+	return removeItem
 }
 
 func (sl *skipList) updateItem(node *node, item Item) bool {
@@ -211,27 +264,51 @@ func (sl *skipList) updateItem(node *node, item Item) bool {
 	return false
 }
 
-// getRank find the rank for an element.
-// Returns 0 when the element cannot be found, rank otherwise.
-// Note that the rank is 1-based
-func (sl *skipList) getRank(item Item) int {
-	var rank int
-	x := sl.header
-	for i := sl.level - 1; i >= 0; i-- {
-		for y := x.level[i].forward; y != nil && !item.Less(y.item); y = x.level[i].forward {
-			rank += x.level[i].span
-			x = y
+// nodeRank returns the 1-based rank of node n, without comparing items: the
+// top level links from n to the end span the nodes after n.
+func (sl *skipList) nodeRank(n *node) int {
+	rank := sl.length
+	for x := n; x != nil; {
+		top := len(x.level) - 1
+		rank -= x.level[top].span
+		x = x.level[top].forward
+	}
+	return rank
+}
+
+// rankSorted sets ranks[i] to the 1-based rank of nodes[i]. The nodes must be
+// sorted by item, in any order among equal items. The position reached at
+// every level only moves forward, so the list is walked once.
+func (sl *skipList) rankSorted(nodes []*node, ranks []int) {
+	var update [DefaultMaxLevel]*node
+	var rank [DefaultMaxLevel]int
+	for i := 0; i < sl.level; i++ {
+		update[i] = sl.header
+	}
+	for j, n := range nodes {
+		x, r := sl.header, 0
+		for i := sl.level - 1; i >= 0; i-- {
+			if rank[i] > r {
+				x, r = update[i], rank[i]
+			}
+			for y := x.level[i].forward; y != nil && y.item.Less(n.item); y = x.level[i].forward {
+				r += x.level[i].span
+				x = y
+			}
+			update[i], rank[i] = x, r
 		}
-		if x.item != nil && !x.item.Less(item) {
-			return rank
+		// x is the last node less than n, so n is among the equal items
+		// after it.
+		for y := x.level[0].forward; y != n; y = y.level[0].forward {
+			r++
 		}
+		ranks[j] = r + 1
 	}
-	return 0
 }
 
 func (sl *skipList) randomLevel() int {
 	lvl := 1
-	for lvl < sl.maxLevel && float32(sl.random.Uint32()&0xFFFF) < DefaultP*0xFFFF {
+	for lvl < sl.maxLevel && float64(sl.random.Uint32()&0xFFFF) < sl.p*0xFFFF {
 		lvl++
 	}
 	return lvl
@@ -297,7 +374,20 @@ type ZSet struct {
 func New() *ZSet {
 	return &ZSet{
 		dict: make(map[string]*node),
-		sl:   newSkipList(DefaultMaxLevel),
+		sl:   newSkipList(Options{}),
+	}
+}
+
+// NewWithOptions creates a new ZSet configured by opts. It panics if P or
+// MaxLevel is out of range.
+func NewWithOptions(opts Options) *ZSet {
+	capacity := opts.InitialCapacity
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &ZSet{
+		dict: make(map[string]*node, capacity),
+		sl:   newSkipList(opts),
 	}
 }
 
@@ -328,29 +418,32 @@ func (zs *ZSet) Remove(key string) (removeItem Item) {
 	return
 }
 
-// Rank return 1-based rank or 0 if not exist
+// Rank return 1-based rank or 0 if not exist. Equal items are ranked in the
+// order Range returns them.
 func (zs *ZSet) Rank(key string, reverse bool) int {
 	node := zs.dict[key]
 	if node != nil {
-		rank := zs.sl.getRank(node.item)
-		if rank > 0 {
-			if reverse {
-				return zs.sl.length - rank + 1
-			}
-			return rank
+		rank := zs.sl.nodeRank(node)
+		if reverse {
+			return zs.sl.length - rank + 1
 		}
+		return rank
 	}
 	return 0
 }
 
+// FindNext returns the first item for which iGreaterThan returns true, and its
+// 1-based rank, or rank 0 if there is no such item.
 func (zs *ZSet) FindNext(iGreaterThan func(i Item) bool) (v Item, rank int) {
 	n, rank := zs.sl.findNext(iGreaterThan)
 	if n == nil {
-		return
+		return v, 0
 	}
 	return n.item, rank
 }
 
+// FindPrev returns the last item for which iLessThan returns true, and its
+// 1-based rank, or rank 0 if there is no such item.
 func (zs *ZSet) FindPrev(iLessThan func(i Item) bool) (v Item, rank int) {
 	n, rank := zs.sl.findPrev(iLessThan)
 	if n == nil {
@@ -524,6 +617,49 @@ func (zs *ZSet) Get(key string) Item {
 	return nil
 }
 
+// GetMany is like Get for several keys, like ZMSCORE in redis. items[i] is nil
+// if keys[i] does not exist.
+func (zs *ZSet) GetMany(keys []string) (items []Item) {
+	items = make([]Item, len(keys))
+	for i, key := range keys {
+		if node, ok := zs.dict[key]; ok {
+			items[i] = node.item
+		}
+	}
+	return
+}
+
+// RankMany is like Rank for several keys: ranks[i] is the 1-based rank of
+// keys[i], or 0 if not exist. The elements are sorted and their ranks are
+// found in a single walk of the list, instead of one search per key.
+func (zs *ZSet) RankMany(keys []string, reverse bool) (ranks []int) {
+	ranks = make([]int, len(keys))
+	index := make([]int, 0, len(keys))
+	nodes := make([]*node, len(keys))
+	for i, key := range keys {
+		if n := zs.dict[key]; n != nil {
+			nodes[i] = n
+			index = append(index, i)
+		}
+	}
+	sort.Slice(index, func(a, b int) bool {
+		return nodes[index[a]].item.Less(nodes[index[b]].item)
+	})
+	sorted := make([]*node, len(index))
+	for j, i := range index {
+		sorted[j] = nodes[i]
+	}
+	sortedRanks := make([]int, len(index))
+	zs.sl.rankSorted(sorted, sortedRanks)
+	for j, i := range index {
+		ranks[i] = sortedRanks[j]
+		if reverse {
+			ranks[i] = zs.sl.length - ranks[i] + 1
+		}
+	}
+	return
+}
+
 // Length return the element count
 func (zs *ZSet) Length() int {
 	return zs.sl.length
@@ -3,10 +3,7 @@
 // Package zset implements sorted set similar to redis zset.
 package zset
 
-import (
-	"math/rand"
-	"time"
-)
+import "math/rand"
 
 const (
 	DefaultMaxLevel = 32   // (1/p)^MaxLevel >= maxNode
@@ -19,55 +16,93 @@ const (
 // When this function returns false, iteration will stop.
 type ItemIterator[T any] func(i T, rank int) bool
 
-type skipListLevel[T any] struct {
-	forward *node[T]
+type skipListLevel[K comparable, T any] struct {
+	forward *node[K, T]
 	span    int
 }
 
 // node is an element of a skip list
-type node[T any] struct {
+type node[K comparable, T any] struct {
+	key      K
 	item     T
-	backward *node[T]
-	level    []skipListLevel[T]
+	backward *node[K, T]
+	level    []skipListLevel[K, T]
 }
 
 // FreeList represents a free list of set node.
-type FreeList[T any] struct {
-	freelist []*node[T]
+type FreeList[K comparable, T any] struct {
+	freelist     []*node[K, T]
+	slabs        *slabs[K, T] // set by NewSlabFreeList
+	hits, misses uint64       // reported by Stats
 }
 
 // NewFreeList creates a new free list.
-func NewFreeList[T any](size int) *FreeList[T] {
-	return &FreeList[T]{freelist: make([]*node[T], 0, size)}
+func NewFreeList[K comparable, T any](size int) *FreeList[K, T] {
+	return &FreeList[K, T]{freelist: make([]*node[K, T], 0, size)}
+}
+
+// len returns the number of nodes held for reuse.
+func (f *FreeList[K, T]) len() int {
+	if f == nil {
+		return 0
+	}
+	if f.slabs != nil {
+		return f.slabs.len()
+	}
+	return len(f.freelist)
 }
 
-func (f *FreeList[T]) newNode(lvl int) (n *node[T]) {
+func (f *FreeList[K, T]) newNode(lvl int) (n *node[K, T]) {
+	if f == nil {
+		n = new(node[K, T])
+		n.level = make([]skipListLevel[K, T], lvl)
+		return
+	}
+	if f.slabs != nil {
+		var recycled bool
+		if n, recycled = f.slabs.newNode(lvl); recycled {
+			f.hits++
+		} else {
+			f.misses++
+		}
+		return
+	}
 	if len(f.freelist) == 0 {
-		n = new(node[T])
-		n.level = make([]skipListLevel[T], lvl)
+		f.misses++
+		n = new(node[K, T])
+		n.level = make([]skipListLevel[K, T], lvl)
 		return
 	}
+	f.hits++
 	index := len(f.freelist) - 1
 	n = f.freelist[index]
 	f.freelist[index] = nil
 	f.freelist = f.freelist[:index]
 
 	if cap(n.level) < lvl {
-		n.level = make([]skipListLevel[T], lvl)
+		n.level = make([]skipListLevel[K, T], lvl)
 	} else {
 		n.level = n.level[:lvl]
 	}
 	return
 }
 
-func (f *FreeList[T]) freeNode(n *node[T]) (out bool) {
+func (f *FreeList[K, T]) freeNode(n *node[K, T]) (out bool) {
 	// for gc
+	var zeroKey K
 	var zero T
-	n.item = zero
+	n.key, n.item = zeroKey, zero
 	for j := 0; j < len(n.level); j++ {
-		n.level[j] = skipListLevel[T]{}
+		n.level[j] = skipListLevel[K, T]{}
 	}
 
+	if f == nil {
+		return false
+	}
+	if f.slabs != nil {
+		f.slabs.freeNode(n)
+		return true
+	}
 	if len(f.freelist) < cap(f.freelist) {
 		f.freelist = append(f.freelist, n)
 		out = true
@@ -76,50 +111,32 @@ func (f *FreeList[T]) freeNode(n *node[T]) (out bool) {
 }
 
 // skipList represents a skip list
-type skipList[T any] struct {
-	header, tail *node[T]
+type skipList[K comparable, T any] struct {
+	counters counters // reported by Stats
+
+	header, tail *node[K, T]
 	length       int
 	level        int // current level count
 	maxLevel     int
-	freelist     *FreeList[T]
+	p            float64 // probability of one more level
+	freelist     *FreeList[K, T]
 	random       *rand.Rand
 	less         LessFunc[T]
-}
-
-// newSkipList creates a skip list
-func newSkipList[T any](maxLevel int, less LessFunc[T]) *skipList[T] {
-	if maxLevel < DefaultMaxLevel {
-		panic("maxLevel must < 32")
-	}
-	return &skipList[T]{
-		level: 1,
-		header: &node[T]{
-			level: make([]skipListLevel[T], maxLevel),
-		},
-		maxLevel: maxLevel,
-		freelist: NewFreeList[T](DefaultFreeListSize),
-		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
-		less:     less,
-	}
+	aug          augmenter[K, T]
+	// finger is a search path: the last node at each level ranked at or
+	// before a position, and their ranks in fingerRank. Searches start from
+	// it. Empty if there is none. With useFinger, it is the path of the last
+	// insert, otherwise it is only set by hint, for the next insert.
+	finger     []*node[K, T]
+	fingerRank []int
+	useFinger  bool
 }
 
 // insert an item into the SkipList.
-func (sl *skipList[T]) insert(item T) *node[T] {
-	var update [DefaultMaxLevel]*node[T] // [0...list.maxLevel)
+func (sl *skipList[K, T]) insert(key K, item T) *node[K, T] {
+	var update [DefaultMaxLevel]*node[K, T] // [0...list.maxLevel)
 	var rank [DefaultMaxLevel]int
-	x := sl.header
-	for i := sl.level - 1; i >= 0; i-- {
-		if i == sl.level-1 {
-			rank[i] = 0
-		} else {
-			rank[i] = rank[i+1]
-		}
-		for y := x.level[i].forward; y != nil && sl.less(y.item, item); y = x.level[i].forward {
-			rank[i] += x.level[i].span
-			x = y
-		}
-		update[i] = x
-	}
+	sl.search(item, update[:], rank[:])
 
 	lvl := sl.randomLevel()
 	if lvl > sl.level {
@@ -131,8 +148,8 @@ func (sl *skipList[T]) insert(item T) *node[T] {
 		sl.level = lvl
 	}
 
-	x = sl.freelist.newNode(lvl)
-	x.item = item
+	x := sl.freelist.newNode(lvl)
+	x.key, x.item = key, item
 	for i := 0; i < lvl; i++ {
 		x.level[i].forward = update[i].level[i].forward
 		update[i].level[i].forward = x
@@ -146,6 +163,16 @@ func (sl *skipList[T]) insert(item T) *node[T] {
 		update[i].level[i].span++
 	}
 
+	if sl.aug != nil {
+		sl.aug.init(x)
+		for i := 0; i < sl.level; i++ {
+			if i < lvl {
+				sl.aug.update(x, i)
+			}
+			sl.aug.update(update[i], i)
+		}
+	}
+
 	if update[0] == sl.header {
 		x.backward = nil
 	} else {
@@ -157,135 +184,274 @@ func (sl *skipList[T]) insert(item T) *node[T] {
 		x.level[0].forward.backward = x
 	}
 	sl.length++
+
+	// move the finger just before x, so that searching next to it is cheap.
+	if sl.useFinger {
+		sl.setFinger(update[:], rank[:])
+	} else {
+		sl.dropFinger()
+	}
 	return x
 }
 
-// delete element
-func (sl *skipList[T]) delete(n *node[T]) (_ T) {
-	var preAlloc [DefaultMaxLevel]*node[T] // [0...list.maxLevel)
-	update := preAlloc[:sl.maxLevel]
-	x := sl.header
-	for i := sl.level - 1; i >= 0; i-- {
-		for y := x.level[i].forward; y != nil && sl.less(y.item, n.item); y = x.level[i].forward {
-			x = y
+// load appends count items, starting at node n and following level 0, to the
+// empty skip list in linear time.
+func (sl *skipList[K, T]) load(n *node[K, T], count int) {
+	var update [DefaultMaxLevel]*node[K, T]
+	var rank [DefaultMaxLevel]int
+	for i := range update {
+		update[i] = sl.header
+	}
+	var prev *node[K, T]
+	for r := 1; r <= count; r++ {
+		lvl := sl.randomLevel()
+		if lvl > sl.level {
+			sl.level = lvl
 		}
-		update[i] = x
+		x := sl.freelist.newNode(lvl)
+		x.key, x.item = n.key, n.item
+		for i := 0; i < lvl; i++ {
+			update[i].level[i].forward = x
+			update[i].level[i].span = r - rank[i]
+			update[i], rank[i] = x, r
+		}
+		x.backward = prev
+		prev = x
+		n = n.level[0].forward
+	}
+	for i := 0; i < sl.level; i++ {
+		update[i].level[i].span = count - rank[i]
 	}
-	x = x.level[0].forward
-	if x != nil && !sl.less(n.item, x.item) {
+	sl.tail = prev
+	sl.length = count
+	sl.dropFinger()
+}
+
+// findUpdate fills update with the predecessors of node n at every level, and
+// rank with their ranks. It returns the rank of n. Unlike search, it leaves the
+// finger where it is.
+func (sl *skipList[K, T]) findUpdate(n *node[K, T], update []*node[K, T], rank []int) int {
+	if n.backward == nil {
+		// the first node, as when trimming the head of a time ordered set.
 		for i := 0; i < sl.level; i++ {
-			if update[i].level[i].forward == x {
-				update[i].level[i].span += x.level[i].span - 1
-				update[i].level[i].forward = x.level[i].forward
-			} else {
-				update[i].level[i].span--
-			}
-		}
-		for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
-			sl.level--
+			update[i], rank[i] = sl.header, 0
 		}
-		if x.level[0].forward == nil {
-			sl.tail = x.backward
-		} else {
-			x.level[0].forward.backward = x.backward
+		sl.counters.search(0)
+		return 1
+	}
+	x, r, steps, compares := sl.header, 0, 0, 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for y := x.level[i].forward; y != nil && sl.lessCount(y.item, n.item, &compares); y = x.level[i].forward {
+			r += x.level[i].span
+			x = y
+			steps++
 		}
-		removeItem := x.item
-		sl.freelist.freeNode(x)
-		sl.length--
-		return removeItem
+		update[i], rank[i] = x, r
 	}
-	return
+	sl.counters.search(steps)
+	sl.counters.compare(compares)
+	if x.level[0].forward != n {
+		// items equal to n are ranked before it: find its predecessors by
+		// rank rather than by walking them.
+		r = sl.nodeRank(n) - 1
+		sl.searchRank(r, update, rank)
+	}
+	return r + 1
 }
 
-func (sl *skipList[T]) updateItem(node *node[T], item T) bool {
-	if (node.level[0].forward == nil || !sl.less(node.level[0].forward.item, item)) &&
-		(node.backward == nil || !sl.less(item, node.backward.item)) {
-		node.item = item
+// delete element
+func (sl *skipList[K, T]) delete(n *node[K, T]) (_ T) {
+	var update [DefaultMaxLevel]*node[K, T] // [0...list.maxLevel)
+	var rank [DefaultMaxLevel]int
+	x := n
+	xRank := sl.findUpdate(x, update[:], rank[:])
+	for i := 0; i < sl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if sl.aug != nil {
+		sl.aug.free(x)
+		for i := 0; i < sl.level; i++ {
+			sl.aug.update(update[i], i)
+		}
+	}
+	for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		sl.level--
+	}
+	if x.level[0].forward == nil {
+		sl.tail = x.backward
+	} else {
+		x.level[0].forward.backward = x.backward
+	}
+	// the predecessors of x keep their ranks.
+	sl.unlinkFinger(x, xRank, update[:], rank[:])
+	removeItem := x.item
+	sl.freelist.freeNode(x)
+	sl.length--
+	return removeItem
+}
+
+// fits reports whether item can replace the item of node n without moving it.
+func (sl *skipList[K, T]) fits(n *node[K, T], item T) bool {
+	compares := 0
+	fits := (n.level[0].forward == nil || !sl.lessCount(n.level[0].forward.item, item, &compares)) &&
+		(n.backward == nil || !sl.lessCount(item, n.backward.item, &compares))
+	sl.counters.compare(compares)
+	return fits
+}
+
+func (sl *skipList[K, T]) updateItem(n *node[K, T], item T) bool {
+	if sl.fits(n, item) {
+		n.item = item
+		if sl.aug != nil {
+			var update [DefaultMaxLevel]*node[K, T]
+			var rank [DefaultMaxLevel]int
+			sl.findUpdate(n, update[:], rank[:])
+			for i := 0; i < sl.level; i++ {
+				sl.aug.update(update[i], i)
+			}
+		}
 		return true
 	}
 	return false
 }
 
-// getRank find the rank for an element.
-// Returns 0 when the element cannot be found, rank otherwise.
-// Note that the rank is 1-based
-func (sl *skipList[T]) getRank(item T) int {
-	var rank int
-	x := sl.header
-	for i := sl.level - 1; i >= 0; i-- {
-		for y := x.level[i].forward; y != nil && !sl.less(item, y.item); y = x.level[i].forward {
-			rank += x.level[i].span
-			x = y
-		}
-		if x != sl.header && !sl.less(x.item, item) {
-			return rank
-		}
-	}
-	return 0
+// lessThan calls the LessFunc of the list, and counts the call.
+func (sl *skipList[K, T]) lessThan(a, b T) bool {
+	sl.counters.compare(1)
+	return sl.less(a, b)
 }
 
-func (sl *skipList[T]) randomLevel() int {
+// lessCount calls the LessFunc of the list, and counts the call in *compares,
+// for the caller to add to the counters once per operation.
+func (sl *skipList[K, T]) lessCount(a, b T, compares *int) bool {
+	*compares++
+	return sl.less(a, b)
+}
+
+func (sl *skipList[K, T]) randomLevel() int {
 	lvl := 1
-	for lvl < sl.maxLevel && float32(sl.random.Uint32()&0xFFFF) < DefaultP*0xFFFF {
+	for lvl < sl.maxLevel && float64(sl.random.Uint32()&0xFFFF) < sl.p*0xFFFF {
 		lvl++
 	}
 	return lvl
 }
 
 // Finds an element by its rank. The rank argument needs to be 1-based.
-func (sl *skipList[T]) getNodeByRank(rank int) *node[T] {
-	var traversed int
+func (sl *skipList[K, T]) getNodeByRank(rank int) *node[K, T] {
+	var traversed, steps int
 	x := sl.header
 	for i := sl.level - 1; i >= 0; i-- {
 		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
 			traversed += x.level[i].span
 			x = x.level[i].forward
+			steps++
 		}
 		if traversed == rank {
+			sl.counters.search(steps)
 			return x
 		}
 	}
+	sl.counters.search(steps)
 	return nil
 }
 
-func (sl *skipList[T]) getMinNode() *node[T] {
+// advance finds an element by its rank, starting from node x whose rank is
+// rank. It climbs the levels of the nodes it passes, so the cost depends on the
+// distance rather than the list length. The target must be in [rank, length].
+// A nil x starts from the header.
+func (sl *skipList[K, T]) advance(x *node[K, T], rank, target int) *node[K, T] {
+	if x == nil {
+		x = sl.header
+	}
+	for rank < target {
+		i := len(x.level) - 1
+		for x.level[i].forward == nil || rank+x.level[i].span > target {
+			i--
+		}
+		rank += x.level[i].span
+		x = x.level[i].forward
+	}
+	return x
+}
+
+func (sl *skipList[K, T]) len() int {
+	return sl.length
+}
+
+// empty returns a new empty skip list with the same settings.
+func (sl *skipList[K, T]) empty() backend[K, T] {
+	return newSkipList(sl.less, Options[K, T]{MaxLevel: sl.maxLevel, P: sl.p})
+}
+
+func (sl *skipList[K, T]) getMinNode() *node[K, T] {
 	return sl.header.level[0].forward
 }
 
-func (sl *skipList[T]) getMaxNode() *node[T] {
+func (sl *skipList[K, T]) getMaxNode() *node[K, T] {
 	return sl.tail
 }
 
 // return the first node greater and the node's 1-based rank.
-func (sl *skipList[T]) findNext(greater func(i T) bool) (*node[T], int) {
+func (sl *skipList[K, T]) findNext(greater func(i T) bool) (*node[K, T], int) {
+	if sl.useFinger && len(sl.finger) > 0 {
+		x, rank := sl.climbLast(func(i T) bool { return !greater(i) })
+		return x.level[0].forward, rank + x.level[0].span
+	}
 	x := sl.header
-	var rank int
+	var rank, steps int
 	for i := sl.level - 1; i >= 0; i-- {
 		for y := x.level[i].forward; y != nil && !greater(y.item); y = x.level[i].forward {
 			rank += x.level[i].span
 			x = y
+			steps++
 		}
 	}
+	sl.counters.search(steps)
 	return x.level[0].forward, rank + x.level[0].span
 }
 
 // return the first node less and the node's 1-based rank.
-func (sl *skipList[T]) findPrev(less func(i T) bool) (*node[T], int) {
-	var rank int
+func (sl *skipList[K, T]) findPrev(less func(i T) bool) (*node[K, T], int) {
+	if sl.useFinger && len(sl.finger) > 0 {
+		return sl.climbLast(less)
+	}
+	var rank, steps int
 	x := sl.header
 	for i := sl.level - 1; i >= 0; i-- {
 		for y := x.level[i].forward; y != nil && less(y.item); y = x.level[i].forward {
 			rank += x.level[i].span
 			x = y
+			steps++
 		}
 	}
+	sl.counters.search(steps)
 	return x, rank
 }
 
+// rank returns the 1-based rank of node n, which must be in the list.
+func (sl *skipList[K, T]) rank(n *node[K, T], reverse bool) int {
+	rank := sl.nodeRank(n)
+	if reverse {
+		return sl.length - rank + 1
+	}
+	return rank
+}
+
 // ZSet set
 type ZSet[K comparable, T any] struct {
-	dict map[K]*node[T]
-	sl   *skipList[T]
+	dict      map[K]*node[K, T]
+	ord       backend[K, T]
+	sl        *skipList[K, T] // ord if it is a skip list
+	observers []*observer[K, T]
+	hold      bool              // hold back events in held, during a commit
+	held      []heldEvent[K, T] // events not delivered yet
+	compact   *Options[K, T]    // options to promote with, nil if dict is used
+	undo      *undoLog[K, T]    // nil without savepoints
 }
 
 // LessFunc determines how to order a type 'T'.  It should implement a strict
@@ -294,64 +460,104 @@ type LessFunc[T any] func(a, b T) bool
 
 // New creates a new ZSet.
 func New[K comparable, T any](less LessFunc[T]) *ZSet[K, T] {
-	return &ZSet[K, T]{
-		dict: make(map[K]*node[T]),
-		sl:   newSkipList[T](DefaultMaxLevel, less),
+	return newZSet(newSkipList(less, Options[K, T]{}), 0)
+}
+
+// newZSet creates a ZSet ordered by ord, with a dict sized for capacity keys.
+func newZSet[K comparable, T any](ord backend[K, T], capacity int) *ZSet[K, T] {
+	zs := &ZSet[K, T]{
+		dict: make(map[K]*node[K, T], capacity),
+		ord:  ord,
 	}
+	zs.sl, _ = ord.(*skipList[K, T])
+	return zs
 }
 
 // Add a new element or update the score of an existing element. If an item already
 // exist, the removed item is returned. Otherwise, nil is returned.
 func (zs *ZSet[K, T]) Add(key K, item T) (removeItem T) {
-	if node := zs.dict[key]; node != nil {
+	if zs.undo != nil {
+		zs.recordUndo(key)
+	}
+	if len(zs.observers) == 0 {
+		return zs.add(key, item)
+	}
+	e := Event[K, T]{Type: EventAdd, Key: key, New: item}
+	if n := zs.lookup(key); n != nil {
+		e.Type, e.Old, e.OldRank = EventUpdate, n.item, zs.ord.rank(n, false)
+	}
+	removeItem = zs.add(key, item)
+	e.NewRank = zs.ord.rank(zs.lookup(key), false)
+	zs.notify(e)
+	return
+}
+
+func (zs *ZSet[K, T]) add(key K, item T) (removeItem T) {
+	if node := zs.lookup(key); node != nil {
 		// if the node after update, would be still exactly at the same position,
 		// we can just update item.
-		if zs.sl.updateItem(node, item) {
+		if zs.ord.updateItem(node, item) {
 			return
 		}
-		removeItem = zs.sl.delete(node)
+		removeItem = zs.ord.delete(node)
+	}
+	node := zs.ord.insert(key, item)
+	if zs.compact == nil {
+		zs.dict[key] = node
+	} else if zs.ord.len() > zs.compact.CompactThreshold {
+		zs.promote()
 	}
-	zs.dict[key] = zs.sl.insert(item)
 	return
 }
 
 // Remove the element 'ele' from the sorted set,
 // return true if the element existed and was deleted, false otherwise
 func (zs *ZSet[K, T]) Remove(key K) (removeItem T) {
-	node := zs.dict[key]
+	node := zs.lookup(key)
 	if node == nil {
 		return
 	}
-	removeItem = zs.sl.delete(node)
-	delete(zs.dict, key)
+	if zs.undo != nil {
+		zs.recordUndo(key)
+	}
+	var rank int
+	if len(zs.observers) > 0 {
+		rank = zs.ord.rank(node, false)
+	}
+	removeItem = zs.ord.delete(node)
+	if zs.compact == nil {
+		delete(zs.dict, key)
+	}
+	if len(zs.observers) > 0 {
+		zs.notify(Event[K, T]{Type: EventRemove, Key: key, Old: removeItem, OldRank: rank})
+	}
 	return
 }
 
-// Rank return 1-based rank or 0 if not exist
+// Rank return 1-based rank or 0 if not exist. Equal items are ranked in the
+// order Range returns them.
 func (zs *ZSet[K, T]) Rank(key K, reverse bool) int {
-	node := zs.dict[key]
+	node := zs.lookup(key)
 	if node != nil {
-		rank := zs.sl.getRank(node.item)
-		if rank > 0 {
-			if reverse {
-				return zs.sl.length - rank + 1
-			}
-			return rank
-		}
+		return zs.ord.rank(node, reverse)
 	}
 	return 0
 }
 
+// FindNext returns the first item for which iGreaterThan returns true, and its
+// 1-based rank, or rank 0 if there is no such item.
 func (zs *ZSet[K, T]) FindNext(iGreaterThan func(i T) bool) (v T, rank int) {
-	n, rank := zs.sl.findNext(iGreaterThan)
+	n, rank := zs.ord.findNext(iGreaterThan)
 	if n == nil {
-		return
+		return v, 0
 	}
 	return n.item, rank
 }
 
+// FindPrev returns the last item for which iLessThan returns true, and its
+// 1-based rank, or rank 0 if there is no such item.
 func (zs *ZSet[K, T]) FindPrev(iLessThan func(i T) bool) (v T, rank int) {
-	n, rank := zs.sl.findPrev(iLessThan)
+	n, rank := zs.ord.findPrev(iLessThan)
 	if n == nil {
 		return
 	}
@@ -362,29 +568,38 @@ func (zs *ZSet[K, T]) FindPrev(iLessThan func(i T) bool) (v T, rank int) {
 // until iterator return false. If min is nil, it represents negative infinity.
 // If max is nil, it represents positive infinity.
 func (zs *ZSet[K, T]) RangeByScore(min, max func(i T) bool, reverse bool, iterator ItemIterator[T]) {
-	llen := zs.sl.length
-	var minNode, maxNode *node[T]
-	var minRank, maxRank int
-	if min == nil {
-		minNode = zs.sl.getMinNode()
-		minRank = 1
-	} else {
-		minNode, minRank = zs.sl.findNext(min)
-	}
-	if minNode == nil {
+	rangeByScore(zs.ord, min, max, reverse, iterator)
+}
+
+// RangeByScoreLimit is like RangeByScore, but skips the first offset values of
+// the range and visits at most count values, like ZRANGEBYSCORE ... LIMIT in
+// redis. A negative count means no limit. The skipped values are jumped over
+// by rank instead of being iterated.
+func (zs *ZSet[K, T]) RangeByScoreLimit(min, max func(i T) bool, reverse bool, offset, count int, iterator ItemIterator[T]) {
+	if offset < 0 || count == 0 {
 		return
 	}
-	if max == nil {
-		maxNode = zs.sl.getMaxNode()
-		maxRank = llen
-	} else {
-		maxNode, maxRank = zs.sl.findPrev(max)
+	llen := zs.ord.len()
+	minNode, minRank := zs.ord.getMinNode(), 1
+	if min != nil {
+		minNode, minRank = zs.ord.findNext(min)
 	}
-	if maxNode == nil {
+	if minNode == nil {
 		return
 	}
+	maxRank := llen
+	if max != nil {
+		_, maxRank = zs.ord.findPrev(max)
+	}
 	if reverse {
-		n := maxNode
+		maxRank -= offset
+		if count > 0 && maxRank-count+1 > minRank {
+			minRank = maxRank - count + 1
+		}
+		if maxRank < minRank {
+			return
+		}
+		n := zs.ord.getNodeByRank(maxRank)
 		for i := maxRank; i >= minRank; i-- {
 			if iterator(n.item, llen-i+1) {
 				n = n.backward
@@ -393,8 +608,14 @@ func (zs *ZSet[K, T]) RangeByScore(min, max func(i T) bool, reverse bool, iterat
 			}
 		}
 	} else {
-		n := minNode
-		for i := minRank; i <= maxRank; i++ {
+		if count > 0 && minRank+offset+count-1 < maxRank {
+			maxRank = minRank + offset + count - 1
+		}
+		if minRank+offset > maxRank {
+			return
+		}
+		n := zs.ord.advance(minNode, minRank, minRank+offset)
+		for i := minRank + offset; i <= maxRank; i++ {
 			if iterator(n.item, i) {
 				n = n.level[0].forward
 			} else {
@@ -408,47 +629,36 @@ func (zs *ZSet[K, T]) RangeByScore(min, max func(i T) bool, reverse bool, iterat
 // until iterator return false. The <start> and <stop> arguments represent
 // zero-based indexes.
 func (zs *ZSet[K, T]) Range(start, end int, reverse bool, iterator ItemIterator[T]) {
-	llen := zs.sl.length
-	if start < 0 {
-		start = llen + start
-	}
-	if end < 0 {
-		end = llen + end
-	}
-	if start < 0 {
-		start = 0
-	}
-	if start > end || start >= llen {
-		return
-	}
-	if end >= llen {
-		end = llen - 1
-	}
+	rangeByRank(zs.ord, start, end, reverse, iterator)
+}
+
+// RangeWithKeys is like Range, but also passes the key of every element to
+// the iterator.
+func (zs *ZSet[K, T]) RangeWithKeys(start, end int, reverse bool, iterator func(key K, i T, rank int) bool) {
+	rangeNodes(zs.ord, start, end, reverse, func(n *node[K, T], rank int) bool {
+		return iterator(n.key, n.item, rank)
+	})
+}
+
+// iterNode is a node visited by a RangeIterator.
+type iterNode[T any] interface {
+	value() T
+	next(reverse bool) iterNode[T]
+}
+
+func (n *node[K, T]) value() T {
+	return n.item
+}
 
-	rangeLen := end - start + 1
+func (n *node[K, T]) next(reverse bool) iterNode[T] {
 	if reverse {
-		ln := zs.sl.getNodeByRank(llen - start)
-		for i := 1; i <= rangeLen; i++ {
-			if iterator(ln.item, start+i) {
-				ln = ln.backward
-			} else {
-				break
-			}
-		}
-	} else {
-		ln := zs.sl.getNodeByRank(start + 1)
-		for i := 1; i <= rangeLen; i++ {
-			if iterator(ln.item, start+i) {
-				ln = ln.level[0].forward
-			} else {
-				break
-			}
-		}
+		return n.backward
 	}
+	return n.level[0].forward
 }
 
 type RangeIterator[T any] struct {
-	node            *node[T]
+	node            iterNode[T]
 	start, end, cur int
 	reverse         bool
 }
@@ -462,16 +672,12 @@ func (r *RangeIterator[T]) Valid() bool {
 }
 
 func (r *RangeIterator[T]) Next() {
-	if r.reverse {
-		r.node = r.node.backward
-	} else {
-		r.node = r.node.level[0].forward
-	}
+	r.node = r.node.next(r.reverse)
 	r.cur++
 }
 
 func (r *RangeIterator[T]) Item() T {
-	return r.node.item
+	return r.node.value()
 }
 
 func (r *RangeIterator[T]) Rank() int {
@@ -481,7 +687,7 @@ func (r *RangeIterator[T]) Rank() int {
 // RangeIterator return iterator for visit elements in [start, end].
 // It is slower than Range.
 func (zs *ZSet[K, T]) RangeIterator(start, end int, reverse bool) RangeIterator[T] {
-	llen := zs.sl.length
+	llen := zs.ord.len()
 	if start < 0 {
 		start = llen + start
 	}
@@ -500,11 +706,11 @@ func (zs *ZSet[K, T]) RangeIterator(start, end int, reverse bool) RangeIterator[
 		end = llen - 1
 	}
 
-	var n *node[T]
+	var n *node[K, T]
 	if reverse {
-		n = zs.sl.getNodeByRank(llen - start)
+		n = zs.ord.getNodeByRank(llen - start)
 	} else {
-		n = zs.sl.getNodeByRank(start + 1)
+		n = zs.ord.getNodeByRank(start + 1)
 	}
 	return RangeIterator[T]{
 		start:   start,
@@ -517,13 +723,13 @@ func (zs *ZSet[K, T]) RangeIterator(start, end int, reverse bool) RangeIterator[
 
 // Get return Item in dict.
 func (zs *ZSet[K, T]) Get(key K) (item T, found bool) {
-	if n, ok := zs.dict[key]; ok {
-		return n.item, ok
+	if n := zs.lookup(key); n != nil {
+		return n.item, true
 	}
 	return
 }
 
 // Length return the element count
 func (zs *ZSet[K, T]) Length() int {
-	return zs.sl.length
+	return zs.ord.len()
 }
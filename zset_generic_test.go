@@ -107,6 +107,98 @@ func TestZSetRank(t *testing.T) {
 	}
 }
 
+func TestRankTied(t *testing.T) {
+	for _, backend := range []Backend{BackendSkipList, BackendBTree} {
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{Backend: backend, Seed: 1})
+		// equal items under the LessFunc, around other items.
+		zs.Add("x", TestRank{member: "x", score: 0})
+		for _, key := range []string{"a", "b", "c"} {
+			zs.Add(key, TestRank{member: key, score: 1})
+		}
+		zs.Add("y", TestRank{member: "y", score: 2})
+		var keys []string
+		zs.Range(0, -1, false, func(i TestRank, rank int) bool {
+			keys = append(keys, i.member)
+			return true
+		})
+		// each element is ranked at its own position, as Range returns it.
+		for i, key := range keys {
+			if r := zs.Rank(key, false); r != i+1 {
+				t.Error("Rank error", backend, keys, key, r)
+			}
+			if r := zs.Rank(key, true); r != len(keys)-i {
+				t.Error("Rank reverse error", backend, keys, key, r)
+			}
+		}
+	}
+}
+
+func TestFindNotFound(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(10) {
+		zs.Add(v.member, v)
+	}
+	if v, rank := zs.FindNext(func(i TestRank) bool { return i.score >= 10 }); rank != 0 || v != (TestRank{}) {
+		t.Error("FindNext error", v, rank)
+	}
+	if v, rank := zs.FindPrev(func(i TestRank) bool { return i.score < 0 }); rank != 0 || v != (TestRank{}) {
+		t.Error("FindPrev error", v, rank)
+	}
+}
+
+func TestRemoveTied(t *testing.T) {
+	for _, backend := range []Backend{BackendSkipList, BackendBTree} {
+		for _, remove := range []string{"a", "b", "c"} {
+			zs := NewWithOptions(func(a, b TestRank) bool {
+				return a.score < b.score
+			}, Options[string, TestRank]{Backend: backend})
+			// equal items under the LessFunc.
+			for _, key := range []string{"a", "b", "c"} {
+				zs.Add(key, TestRank{member: key, score: 1})
+			}
+			zs.Remove(remove)
+			var keys []string
+			zs.Range(0, -1, false, func(i TestRank, rank int) bool {
+				keys = append(keys, i.member)
+				return true
+			})
+			for _, key := range keys {
+				if key == remove {
+					t.Fatal("Remove removed another element", backend, remove, keys)
+				}
+			}
+			if _, found := zs.Get(remove); len(keys) != 2 || found {
+				t.Fatal("Remove error", backend, remove, keys)
+			}
+		}
+
+		// removing among many ties finds the node by rank, not by walking
+		// the ties.
+		zs := NewWithOptions(func(a, b TestRank) bool {
+			return a.score < b.score
+		}, Options[string, TestRank]{Backend: backend})
+		const n = 10000
+		for i := 0; i < n; i++ {
+			zs.Add(strconv.Itoa(i), TestRank{member: strconv.Itoa(i), score: 0})
+		}
+		before := zs.Stats()
+		for i := 0; i < n; i += 2 {
+			zs.Remove(strconv.Itoa(i))
+		}
+		s := zs.Stats()
+		if avg := float64(s.SearchSteps-before.SearchSteps) / (n / 2); avg > 50 {
+			t.Error(backend, "too many steps per Remove among ties", avg)
+		}
+		if err := zs.Validate(); err != nil || zs.Length() != n/2 {
+			t.Fatal(backend, err, zs.Length())
+		}
+	}
+}
+
 func TestRangeItem(t *testing.T) {
 	zs := New[string, TestRank](func(a, b TestRank) bool {
 		return a.score < b.score
@@ -169,174 +261,266 @@ func TestRangeItem(t *testing.T) {
 	}
 }
 
-const benchmarkListSize = 10000
+func TestRangeWithKeys(t *testing.T) {
+	zs := New[string](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	for _, v := range perm(10) {
+		zs.Add(v.member, v)
+	}
+	var keys []string
+	zs.RangeWithKeys(2, 4, true, func(key string, v TestRank, rank int) bool {
+		if key != v.member || zs.Rank(key, true) != rank {
+			t.Error("RangeWithKeys error", key, v, rank)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []string{"7", "6", "5"}) {
+		t.Error("RangeWithKeys error", keys)
+	}
+}
 
-func BenchmarkAdd(b *testing.B) {
-	b.StopTimer()
-	insertP := perm(benchmarkListSize)
-	b.StartTimer()
-	i := 0
-	for i < b.N {
-		tr := New[string, TestRank](func(a, b TestRank) bool {
-			return a.score < b.score
+func TestRangeByScoreLimit(t *testing.T) {
+	zs := New[string, TestRank](func(a, b TestRank) bool {
+		return a.score < b.score
+	})
+	zs.RangeByScoreLimit(nil, nil, false, 0, 10, func(i TestRank, rank int) bool {
+		t.Error("RangeByScoreLimit on empty set")
+		return true
+	})
+	for _, i := range perm(100) {
+		zs.Add(i.member, i)
+	}
+
+	min := func(i TestRank) bool {
+		return i.score >= 10
+	}
+	max := func(i TestRank) bool {
+		return i.score <= 89
+	}
+	for _, reverse := range []bool{false, true} {
+		var all []TestRank
+		var allRanks []int
+		zs.RangeByScore(min, max, reverse, func(i TestRank, rank int) bool {
+			all = append(all, i)
+			allRanks = append(allRanks, rank)
+			return true
 		})
-		for _, item := range insertP {
-			tr.Add(item.member, item)
-			i++
-			if i >= b.N {
-				return
+		for offset := 0; offset <= 90; offset += 7 {
+			for _, count := range []int{-1, 0, 1, 5, 100} {
+				var r []TestRank
+				var ranks []int
+				zs.RangeByScoreLimit(min, max, reverse, offset, count, func(i TestRank, rank int) bool {
+					r = append(r, i)
+					ranks = append(ranks, rank)
+					return true
+				})
+				end := len(all)
+				if count >= 0 && offset+count < end {
+					end = offset + count
+				}
+				var expect []TestRank
+				var expectRanks []int
+				if offset < end {
+					expect, expectRanks = all[offset:end], allRanks[offset:end]
+				}
+				if !reflect.DeepEqual(r, expect) || !reflect.DeepEqual(ranks, expectRanks) {
+					t.Error("RangeByScoreLimit error", reverse, offset, count, r, ranks)
+				}
 			}
 		}
 	}
 }
 
-func BenchmarkAddIncrease(b *testing.B) {
-	b.StopTimer()
-	insertP := rang(benchmarkListSize)
-	b.StartTimer()
-	i := 0
-	for i < b.N {
-		tr := New[string, TestRank](func(a, b TestRank) bool {
-			return a.score < b.score
+const benchmarkListSize = 10000
+
+// benchmarkBackends runs bench with every backend.
+func benchmarkBackends(b *testing.B, bench func(b *testing.B, newSet func() *ZSet[string, TestRank])) {
+	for _, backend := range []struct {
+		name    string
+		backend Backend
+	}{
+		{"SkipList", BackendSkipList},
+		{"BTree", BackendBTree},
+	} {
+		backend := backend
+		b.Run(backend.name, func(b *testing.B) {
+			bench(b, func() *ZSet[string, TestRank] {
+				return NewWithOptions(func(a, b TestRank) bool {
+					return a.score < b.score
+				}, Options[string, TestRank]{Backend: backend.backend})
+			})
 		})
-		for _, item := range insertP {
-			tr.Add(item.member, item)
-			i++
-			if i >= b.N {
-				return
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		b.StopTimer()
+		insertP := perm(benchmarkListSize)
+		b.StartTimer()
+		i := 0
+		for i < b.N {
+			tr := newSet()
+			for _, item := range insertP {
+				tr.Add(item.member, item)
+				i++
+				if i >= b.N {
+					return
+				}
 			}
 		}
-	}
+	})
 }
 
-func BenchmarkAddDecrease(b *testing.B) {
-	b.StopTimer()
-	insertP := revrang(benchmarkListSize, benchmarkListSize)
-	b.StartTimer()
-	i := 0
-	for i < b.N {
-		tr := New[string, TestRank](func(a, b TestRank) bool {
-			return a.score < b.score
-		})
-		for _, item := range insertP {
-			tr.Add(item.member, item)
-			i++
-			if i >= b.N {
-				return
+func BenchmarkAddIncrease(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		b.StopTimer()
+		insertP := rang(benchmarkListSize)
+		b.StartTimer()
+		i := 0
+		for i < b.N {
+			tr := newSet()
+			for _, item := range insertP {
+				tr.Add(item.member, item)
+				i++
+				if i >= b.N {
+					return
+				}
 			}
 		}
-	}
+	})
 }
 
-func BenchmarkRemoveAdd(b *testing.B) {
-	b.StopTimer()
-	insertP := perm(benchmarkListSize)
-	tr := New[string, TestRank](func(a, b TestRank) bool {
-		return a.score < b.score
+func BenchmarkAddDecrease(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		b.StopTimer()
+		insertP := revrang(benchmarkListSize, benchmarkListSize)
+		b.StartTimer()
+		i := 0
+		for i < b.N {
+			tr := newSet()
+			for _, item := range insertP {
+				tr.Add(item.member, item)
+				i++
+				if i >= b.N {
+					return
+				}
+			}
+		}
 	})
-	for _, item := range insertP {
-		tr.Add(item.member, item)
-	}
-	b.StartTimer()
-	for i := 0; i < b.N; i++ {
-		tr.Remove(insertP[i%benchmarkListSize].member)
-		item := insertP[i%benchmarkListSize]
-		tr.Add(item.member, item)
-	}
 }
 
-func BenchmarkRemove(b *testing.B) {
-	b.StopTimer()
-	insertP := perm(benchmarkListSize)
-	removeP := perm(benchmarkListSize)
-	b.StartTimer()
-	i := 0
-	for i < b.N {
+func BenchmarkRemoveAdd(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
 		b.StopTimer()
-		tr := New[string, TestRank](func(a, b TestRank) bool {
-			return a.score < b.score
-		})
+		insertP := perm(benchmarkListSize)
+		tr := newSet()
 		for _, item := range insertP {
 			tr.Add(item.member, item)
 		}
 		b.StartTimer()
-		for _, item := range removeP {
-			tr.Remove(item.member)
-			i++
-			if i >= b.N {
-				return
-			}
+		for i := 0; i < b.N; i++ {
+			tr.Remove(insertP[i%benchmarkListSize].member)
+			item := insertP[i%benchmarkListSize]
+			tr.Add(item.member, item)
 		}
-		if tr.Length() > 0 {
-			b.Error(tr.Length())
+	})
+}
+
+func BenchmarkRemove(b *testing.B) {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		b.StopTimer()
+		insertP := perm(benchmarkListSize)
+		removeP := perm(benchmarkListSize)
+		b.StartTimer()
+		i := 0
+		for i < b.N {
+			b.StopTimer()
+			tr := newSet()
+			for _, item := range insertP {
+				tr.Add(item.member, item)
+			}
+			b.StartTimer()
+			for _, item := range removeP {
+				tr.Remove(item.member)
+				i++
+				if i >= b.N {
+					return
+				}
+			}
+			if tr.Length() > 0 {
+				b.Error(tr.Length())
+			}
 		}
-	}
+	})
 }
 
 func BenchmarkRank(b *testing.B) {
-	b.StopTimer()
-	insertP := perm(benchmarkListSize)
-	tr := New[string, TestRank](func(a, b TestRank) bool {
-		return a.score < b.score
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		b.StopTimer()
+		insertP := perm(benchmarkListSize)
+		tr := newSet()
+		for _, item := range insertP {
+			tr.Add(item.member, item)
+		}
+		b.StartTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Rank(insertP[i%benchmarkListSize].member, true)
+		}
 	})
-	for _, item := range insertP {
-		tr.Add(item.member, item)
-	}
-	b.StartTimer()
-	for i := 0; i < b.N; i++ {
-		tr.Rank(insertP[i%benchmarkListSize].member, true)
-	}
 }
 
 func BenchmarkRange(b *testing.B) {
-	insertP := perm(benchmarkListSize)
-	tr := New[string, TestRank](func(a, b TestRank) bool {
-		return a.score < b.score
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		insertP := perm(benchmarkListSize)
+		tr := newSet()
+		for _, item := range insertP {
+			tr.Add(item.member, item)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.Range(0, 100, true, func(i TestRank, rank int) bool {
+				return true
+			})
+		}
 	})
-	for _, item := range insertP {
-		tr.Add(item.member, item)
-	}
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		tr.Range(0, 100, true, func(i TestRank, rank int) bool {
-			return true
-		})
-	}
 }
 
 func BenchmarkRangeIterator(b *testing.B) {
-	insertP := perm(benchmarkListSize)
-	tr := New[string, TestRank](func(a, b TestRank) bool {
-		return a.score < b.score
-	})
-	for _, item := range insertP {
-		tr.Add(item.member, item)
-	}
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		it := tr.RangeIterator(0, 100, true)
-		for ; it.Valid(); it.Next() {
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		insertP := perm(benchmarkListSize)
+		tr := newSet()
+		for _, item := range insertP {
+			tr.Add(item.member, item)
 		}
-	}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			it := tr.RangeIterator(0, 100, true)
+			for ; it.Valid(); it.Next() {
+			}
+		}
+	})
 }
 
 func BenchmarkRangeItem(b *testing.B) {
-	insertP := perm(benchmarkListSize)
-	tr := New[string, TestRank](func(a, b TestRank) bool {
-		return a.score < b.score
+	benchmarkBackends(b, func(b *testing.B, newSet func() *ZSet[string, TestRank]) {
+		insertP := perm(benchmarkListSize)
+		tr := newSet()
+		for _, item := range insertP {
+			tr.Add(item.member, item)
+		}
+		minScore, maxScore := 0, 100
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tr.RangeByScore(func(i TestRank) bool {
+				return i.score >= minScore
+			}, func(i TestRank) bool {
+				return i.score <= maxScore
+			}, true, func(i TestRank, rank int) bool {
+				return true
+			})
+		}
 	})
-	for _, item := range insertP {
-		tr.Add(item.member, item)
-	}
-	minScore, maxScore := 0, 100
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		tr.RangeByScore(func(i TestRank) bool {
-			return i.score >= minScore
-		}, func(i TestRank) bool {
-			return i.score <= maxScore
-		}, true, func(i TestRank, rank int) bool {
-			return true
-		})
-	}
 }
@@ -114,6 +114,67 @@ func TestZSetRank(t *testing.T) {
 	}
 }
 
+func TestRankTied(t *testing.T) {
+	zs := NewWithOptions(Options{Seed: 1})
+	// equal items under Less, around other items.
+	zs.Add("x", TestRank{member: "x", score: 0})
+	for _, key := range []string{"a", "b", "c"} {
+		zs.Add(key, TestRank{member: key, score: 1})
+	}
+	zs.Add("y", TestRank{member: "y", score: 2})
+	var keys []string
+	zs.Range(0, -1, false, func(i Item, rank int) bool {
+		keys = append(keys, i.(TestRank).member)
+		return true
+	})
+	// each element is ranked at its own position, as Range returns it.
+	for i, key := range keys {
+		if r := zs.Rank(key, false); r != i+1 {
+			t.Error("Rank error", keys, key, r)
+		}
+		if r := zs.Rank(key, true); r != len(keys)-i {
+			t.Error("Rank reverse error", keys, key, r)
+		}
+	}
+}
+
+func TestFindNotFound(t *testing.T) {
+	zs := New()
+	for _, v := range perm(10) {
+		zs.Add(v.member, v)
+	}
+	if v, rank := zs.FindNext(func(i Item) bool { return i.(TestRank).score >= 10 }); rank != 0 || v != nil {
+		t.Error("FindNext error", v, rank)
+	}
+	if v, rank := zs.FindPrev(func(i Item) bool { return i.(TestRank).score < 0 }); rank != 0 || v != nil {
+		t.Error("FindPrev error", v, rank)
+	}
+}
+
+func TestRemoveTied(t *testing.T) {
+	for _, remove := range []string{"a", "b", "c"} {
+		zs := New()
+		// equal items under Less.
+		for _, key := range []string{"a", "b", "c"} {
+			zs.Add(key, TestRank{member: key, score: 1})
+		}
+		zs.Remove(remove)
+		var keys []string
+		zs.Range(0, -1, false, func(i Item, rank int) bool {
+			keys = append(keys, i.(TestRank).member)
+			return true
+		})
+		for _, key := range keys {
+			if key == remove {
+				t.Fatal("Remove removed another element", remove, keys)
+			}
+		}
+		if len(keys) != 2 || zs.Get(remove) != nil {
+			t.Fatal("Remove error", remove, keys)
+		}
+	}
+}
+
 func TestRangeItem(t *testing.T) {
 	zs := New()
 	zs.RangeByScore(nil, nil, false, func(i Item, rank int) bool {
@@ -174,6 +235,34 @@ func TestRangeItem(t *testing.T) {
 	}
 }
 
+func TestRankMany(t *testing.T) {
+	zs := New()
+	for i := 0; i < 500; i++ {
+		// few scores, so that there are many ties.
+		key := strconv.Itoa(i)
+		zs.Add(key, TestRank{member: key, score: rand.Intn(20)})
+	}
+	var keys []string
+	for i := 0; i < 200; i++ {
+		// missing and repeated keys.
+		keys = append(keys, strconv.Itoa(rand.Intn(600)))
+	}
+	for _, reverse := range []bool{false, true} {
+		ranks := zs.RankMany(keys, reverse)
+		for i, key := range keys {
+			if want := zs.Rank(key, reverse); ranks[i] != want {
+				t.Fatal("RankMany error", key, reverse, ranks[i], want)
+			}
+		}
+	}
+	items := zs.GetMany(keys)
+	for i, key := range keys {
+		if items[i] != zs.Get(key) {
+			t.Fatal("GetMany error", key, items[i])
+		}
+	}
+}
+
 const benchmarkListSize = 10000
 
 func BenchmarkAdd(b *testing.B) {
@@ -0,0 +1,5 @@
+// Package zsettest provides a conformance suite for sorted sets with the
+// semantics of zset.ZSet, and a sorted slice model to check them against.
+//
+// It requires go1.18 or later.
+package zsettest
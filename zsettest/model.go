@@ -0,0 +1,188 @@
+//go:build go1.18
+
+package zsettest
+
+import (
+	"sort"
+
+	"github.com/liwnn/zset"
+)
+
+// Item is the element type used by the suite.
+type Item struct {
+	Key   string
+	Score int
+}
+
+// Less orders items by score, then by key.
+func Less(a, b Item) bool {
+	if a.Score == b.Score {
+		return a.Key < b.Key
+	}
+	return a.Score < b.Score
+}
+
+// LessScore orders items by score only, so that the items with the same score
+// are equal.
+func LessScore(a, b Item) bool {
+	return a.Score < b.Score
+}
+
+// Set is the part of the zset.ZSet API checked by the suite.
+type Set interface {
+	Add(key string, item Item) Item
+	Remove(key string) Item
+	Get(key string) (Item, bool)
+	Rank(key string, reverse bool) int
+	Length() int
+	Range(start, end int, reverse bool, iterator zset.ItemIterator[Item])
+	RangeByScore(min, max func(i Item) bool, reverse bool, iterator zset.ItemIterator[Item])
+	FindNext(iGreaterThan func(i Item) bool) (Item, int)
+	FindPrev(iLessThan func(i Item) bool) (Item, int)
+}
+
+// Model is a simple and slow Set kept as a sorted slice. Like zset.ZSet, it
+// adds an item before the items equal to it, and keeps an updated item in
+// place if it is still ordered between its neighbors.
+type Model struct {
+	less  zset.LessFunc[Item]
+	items []Item
+}
+
+// NewModel creates an empty model ordered by less.
+func NewModel(less zset.LessFunc[Item]) *Model {
+	return &Model{less: less}
+}
+
+func (m *Model) index(key string) int {
+	for i, item := range m.items {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add adds or replaces the item of key and returns the previous item.
+func (m *Model) Add(key string, item Item) Item {
+	if i := m.index(key); i >= 0 &&
+		(i+1 == len(m.items) || !m.less(m.items[i+1], item)) &&
+		(i == 0 || !m.less(item, m.items[i-1])) {
+		old := m.items[i]
+		m.items[i] = item
+		return old
+	}
+	old := m.Remove(key)
+	i := sort.Search(len(m.items), func(i int) bool {
+		return !m.less(m.items[i], item)
+	})
+	m.items = append(m.items, Item{})
+	copy(m.items[i+1:], m.items[i:])
+	m.items[i] = item
+	return old
+}
+
+// Remove removes the item of key and returns it.
+func (m *Model) Remove(key string) Item {
+	i := m.index(key)
+	if i < 0 {
+		return Item{}
+	}
+	item := m.items[i]
+	m.items = append(m.items[:i], m.items[i+1:]...)
+	return item
+}
+
+// Get returns the item of key.
+func (m *Model) Get(key string) (Item, bool) {
+	if i := m.index(key); i >= 0 {
+		return m.items[i], true
+	}
+	return Item{}, false
+}
+
+// Rank returns the 1-based rank of key, or 0 if not exist.
+func (m *Model) Rank(key string, reverse bool) int {
+	i := m.index(key)
+	if i < 0 {
+		return 0
+	}
+	if reverse {
+		return len(m.items) - i
+	}
+	return i + 1
+}
+
+// Length returns the item count.
+func (m *Model) Length() int {
+	return len(m.items)
+}
+
+// Range calls iterator for the items with in index range [start, end].
+func (m *Model) Range(start, end int, reverse bool, iterator zset.ItemIterator[Item]) {
+	n := len(m.items)
+	if start < 0 {
+		start = n + start
+	}
+	if end < 0 {
+		end = n + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	for i := start; i <= end; i++ {
+		item := m.items[i]
+		if reverse {
+			item = m.items[n-1-i]
+		}
+		if !iterator(item, i+1) {
+			return
+		}
+	}
+}
+
+// RangeByScore calls iterator for the items within [min, max].
+func (m *Model) RangeByScore(min, max func(i Item) bool, reverse bool, iterator zset.ItemIterator[Item]) {
+	n := len(m.items)
+	from, to := 0, n-1
+	if min != nil {
+		from = sort.Search(n, func(i int) bool { return min(m.items[i]) })
+	}
+	if max != nil {
+		to = sort.Search(n, func(i int) bool { return !max(m.items[i]) }) - 1
+	}
+	if reverse {
+		for i := to; i >= from; i-- {
+			if !iterator(m.items[i], n-i) {
+				return
+			}
+		}
+		return
+	}
+	for i := from; i <= to; i++ {
+		if !iterator(m.items[i], i+1) {
+			return
+		}
+	}
+}
+
+// FindNext returns the first item for which iGreaterThan returns true.
+func (m *Model) FindNext(iGreaterThan func(i Item) bool) (Item, int) {
+	i := sort.Search(len(m.items), func(i int) bool { return iGreaterThan(m.items[i]) })
+	if i == len(m.items) {
+		return Item{}, 0
+	}
+	return m.items[i], i + 1
+}
+
+// FindPrev returns the last item for which iLessThan returns true.
+func (m *Model) FindPrev(iLessThan func(i Item) bool) (Item, int) {
+	i := sort.Search(len(m.items), func(i int) bool { return !iLessThan(m.items[i]) })
+	if i == 0 {
+		return Item{}, 0
+	}
+	return m.items[i-1], i
+}
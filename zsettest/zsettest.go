@@ -0,0 +1,244 @@
+//go:build go1.18
+
+package zsettest
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/liwnn/zset"
+)
+
+// validator is implemented by sets that can check their own invariants, like
+// zset.ZSet.
+type validator interface {
+	Validate() error
+}
+
+type visited struct {
+	Item Item
+	Rank int
+}
+
+func collect(f func(iterator zset.ItemIterator[Item])) []visited {
+	var items []visited
+	f(func(i Item, rank int) bool {
+		items = append(items, visited{i, rank})
+		return true
+	})
+	return items
+}
+
+func limit(n int, f func(iterator zset.ItemIterator[Item])) []visited {
+	var items []visited
+	f(func(i Item, rank int) bool {
+		items = append(items, visited{i, rank})
+		return len(items) < n
+	})
+	return items
+}
+
+// Exec decodes ops into a sequence of operations, 3 bytes each: the operation,
+// a key and a score. It applies every operation to set and to a Model, and
+// fails t at the first result that differs. set must be empty and ordered by
+// less, which is Less, or LessScore to check the order of equal items. Exec is
+// meant to be driven by a fuzz target.
+func Exec(t testing.TB, set Set, less zset.LessFunc[Item], ops []byte) {
+	t.Helper()
+	m := NewModel(less)
+	for i := 0; i+3 <= len(ops); i += 3 {
+		op, a, b := ops[i], int(ops[i+1]), int(ops[i+2])
+		key := strconv.Itoa(a % 64)
+		score := b % 32
+		reverse := op&0x80 != 0
+		var got, want interface{}
+		var name string
+		switch op & 0x7f % 9 {
+		case 0, 1:
+			name = "Add"
+			item := Item{Key: key, Score: score}
+			set.Add(key, item)
+			m.Add(key, item)
+			got, want = set.Length(), m.Length()
+		case 2:
+			name = "Remove"
+			got, want = set.Remove(key), m.Remove(key)
+		case 3:
+			name = "Get"
+			gotItem, gotOK := set.Get(key)
+			wantItem, wantOK := m.Get(key)
+			got, want = []interface{}{gotItem, gotOK}, []interface{}{wantItem, wantOK}
+		case 4:
+			name = "Rank"
+			got, want = set.Rank(key, reverse), m.Rank(key, reverse)
+		case 5:
+			name = "Range"
+			start, end := a%40-20, b%40-20
+			got = collect(func(it zset.ItemIterator[Item]) { set.Range(start, end, reverse, it) })
+			want = collect(func(it zset.ItemIterator[Item]) { m.Range(start, end, reverse, it) })
+		case 6:
+			name = "RangeByScore"
+			min := func(i Item) bool { return i.Score >= a%32 }
+			max := func(i Item) bool { return i.Score <= score }
+			if a%7 == 0 {
+				min = nil
+			}
+			if b%5 == 0 {
+				max = nil
+			}
+			n := a%8 + 1
+			got = limit(n, func(it zset.ItemIterator[Item]) { set.RangeByScore(min, max, reverse, it) })
+			want = limit(n, func(it zset.ItemIterator[Item]) { m.RangeByScore(min, max, reverse, it) })
+		case 7:
+			name = "FindNext"
+			next := func(i Item) bool { return !less(i, Item{Key: key, Score: score}) }
+			gotItem, gotRank := set.FindNext(next)
+			wantItem, wantRank := m.FindNext(next)
+			got, want = visited{gotItem, gotRank}, visited{wantItem, wantRank}
+		case 8:
+			name = "FindPrev"
+			prev := func(i Item) bool { return less(i, Item{Key: key, Score: score}) }
+			gotItem, gotRank := set.FindPrev(prev)
+			wantItem, wantRank := m.FindPrev(prev)
+			got, want = visited{gotItem, gotRank}, visited{wantItem, wantRank}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("op %d: %s(%q, %d, %v) = %v, want %v", i/3, name, key, score, reverse, got, want)
+		}
+		if v, ok := set.(validator); ok {
+			if err := v.Validate(); err != nil {
+				t.Fatalf("op %d: %s(%q, %d, %v): %v", i/3, name, key, score, reverse, err)
+			}
+		}
+	}
+	got := collect(func(it zset.ItemIterator[Item]) { set.Range(0, -1, false, it) })
+	want := collect(func(it zset.ItemIterator[Item]) { m.Range(0, -1, false, it) })
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("final items = %v, want %v", got, want)
+	}
+}
+
+// Run runs the conformance suite against the sets made by newSet, which must
+// return an empty set ordered by less. The suite orders by Less, and by
+// LessScore to check the order of equal items.
+func Run(t *testing.T, newSet func(less zset.LessFunc[Item]) Set) {
+	t.Run("Rank", func(t *testing.T) {
+		set := newSet(Less)
+		for i := 0; i < 100; i++ {
+			key := strconv.Itoa(i)
+			set.Add(key, Item{Key: key, Score: (i * 37) % 100})
+		}
+		if set.Length() != 100 {
+			t.Fatalf("Length() = %d, want 100", set.Length())
+		}
+		for i := 0; i < 100; i++ {
+			key := strconv.Itoa(i)
+			want := (i*37)%100 + 1
+			if rank := set.Rank(key, false); rank != want {
+				t.Errorf("Rank(%q, false) = %d, want %d", key, rank, want)
+			}
+			if rank := set.Rank(key, true); rank != 101-want {
+				t.Errorf("Rank(%q, true) = %d, want %d", key, rank, 101-want)
+			}
+		}
+		if rank := set.Rank("none", false); rank != 0 {
+			t.Errorf("Rank(none) = %d, want 0", rank)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		set := newSet(Less)
+		set.Add("a", Item{Key: "a", Score: 1})
+		set.Add("b", Item{Key: "b", Score: 2})
+		set.Add("a", Item{Key: "a", Score: 3})
+		if item, ok := set.Get("a"); !ok || item.Score != 3 {
+			t.Errorf("Get(a) = %v, %v, want score 3", item, ok)
+		}
+		if set.Length() != 2 || set.Rank("a", false) != 2 {
+			t.Errorf("Length() = %d, Rank(a) = %d, want 2, 2", set.Length(), set.Rank("a", false))
+		}
+		if item := set.Remove("a"); item.Score != 3 {
+			t.Errorf("Remove(a) = %v, want score 3", item)
+		}
+		if _, ok := set.Get("a"); ok || set.Length() != 1 {
+			t.Errorf("Get(a) found after Remove, Length() = %d", set.Length())
+		}
+		if item := set.Remove("a"); item != (Item{}) {
+			t.Errorf("Remove(a) twice = %v, want zero", item)
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		set := newSet(Less)
+		for i := 0; i < 10; i++ {
+			key := strconv.Itoa(i)
+			set.Add(key, Item{Key: key, Score: i})
+		}
+		got := collect(func(it zset.ItemIterator[Item]) { set.Range(-3, -1, true, it) })
+		want := []visited{{Item{"2", 2}, 8}, {Item{"1", 1}, 9}, {Item{"0", 0}, 10}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Range(-3, -1, true) = %v, want %v", got, want)
+		}
+		got = collect(func(it zset.ItemIterator[Item]) {
+			set.RangeByScore(func(i Item) bool { return i.Score >= 7 }, nil, true, it)
+		})
+		want = []visited{{Item{"9", 9}, 1}, {Item{"8", 8}, 2}, {Item{"7", 7}, 3}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeByScore(7, +inf, true) = %v, want %v", got, want)
+		}
+		if item, rank := set.FindNext(func(i Item) bool { return i.Score > 4 }); item.Key != "5" || rank != 6 {
+			t.Errorf("FindNext(> 4) = %v, %d, want 5, 6", item, rank)
+		}
+		if item, rank := set.FindPrev(func(i Item) bool { return i.Score < 4 }); item.Key != "3" || rank != 4 {
+			t.Errorf("FindPrev(< 4) = %v, %d, want 3, 4", item, rank)
+		}
+		if _, rank := set.FindNext(func(i Item) bool { return i.Score > 9 }); rank != 0 {
+			t.Errorf("FindNext(> 9) rank = %d, want 0", rank)
+		}
+		if _, rank := set.FindPrev(func(i Item) bool { return i.Score < 0 }); rank != 0 {
+			t.Errorf("FindPrev(< 0) rank = %d, want 0", rank)
+		}
+	})
+
+	t.Run("Ties", func(t *testing.T) {
+		set := newSet(LessScore)
+		for _, key := range []string{"a", "b", "c", "d"} {
+			set.Add(key, Item{Key: key, Score: 1})
+		}
+		// added before the equal items, and kept in place by an update
+		// which leaves them equal.
+		set.Add("b", Item{Key: "b", Score: 1})
+		set.Add("c", Item{Key: "c", Score: 0})
+		got := collect(func(it zset.ItemIterator[Item]) { set.Range(0, -1, false, it) })
+		want := []visited{{Item{"c", 0}, 1}, {Item{"d", 1}, 2}, {Item{"b", 1}, 3}, {Item{"a", 1}, 4}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Range(0, -1, false) = %v, want %v", got, want)
+		}
+		for _, v := range want {
+			if rank := set.Rank(v.Item.Key, false); rank != v.Rank {
+				t.Errorf("Rank(%q, false) = %d, want %d", v.Item.Key, rank, v.Rank)
+			}
+		}
+		if item, rank := set.FindNext(func(i Item) bool { return i.Score >= 1 }); item.Key != "d" || rank != 2 {
+			t.Errorf("FindNext(>= 1) = %v, %d, want d, 2", item, rank)
+		}
+		set.Remove("b")
+		if rank := set.Rank("a", false); rank != 3 {
+			t.Errorf("Rank(a) after Remove(b) = %d, want 3", rank)
+		}
+	})
+
+	ops := make([]byte, 3*5000)
+	seed := uint32(1)
+	for i := range ops {
+		seed = seed*1664525 + 1013904223
+		ops[i] = byte(seed >> 24)
+	}
+	t.Run("Random", func(t *testing.T) {
+		Exec(t, newSet(Less), Less, ops)
+	})
+	t.Run("RandomTies", func(t *testing.T) {
+		Exec(t, newSet(LessScore), LessScore, ops)
+	})
+}
@@ -0,0 +1,34 @@
+//go:build go1.18
+
+package zsettest_test
+
+import (
+	"testing"
+
+	"github.com/liwnn/zset"
+	"github.com/liwnn/zset/zsettest"
+)
+
+func TestZSet(t *testing.T) {
+	zsettest.Run(t, func(less zset.LessFunc[zsettest.Item]) zsettest.Set {
+		return zset.New[string](less)
+	})
+}
+
+func TestModel(t *testing.T) {
+	zsettest.Run(t, func(less zset.LessFunc[zsettest.Item]) zsettest.Set {
+		return zsettest.NewModel(less)
+	})
+}
+
+func TestCompactZSet(t *testing.T) {
+	zsettest.Run(t, func(less zset.LessFunc[zsettest.Item]) zsettest.Set {
+		return zset.NewWithOptions(less, zset.Options[string, zsettest.Item]{CompactThreshold: 16})
+	})
+}
+
+func TestBTreeZSet(t *testing.T) {
+	zsettest.Run(t, func(less zset.LessFunc[zsettest.Item]) zsettest.Set {
+		return zset.NewWithOptions(less, zset.Options[string, zsettest.Item]{Backend: zset.BackendBTree, BTreeDegree: 4})
+	})
+}